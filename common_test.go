@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/gildas/go-errors"
@@ -55,6 +56,90 @@ func (stream *BogusStream) Clone() logger.Streamer {
 	return &BogusStream{}
 }
 
+// RecordingStream is a stream that keeps a copy of the records it writes
+//
+// If Gate is not nil, writes wait until it is closed.
+//
+// If FilterLevels is empty, all levels should be written
+type RecordingStream struct {
+	Gate         chan struct{}
+	FilterLevels logger.LevelSet
+	SourceInfo   bool
+	records      []*logger.Record
+	closed       bool
+	mutex        sync.Mutex
+}
+
+func (stream *RecordingStream) GetFilterLevels() logger.LevelSet {
+	if len(stream.FilterLevels) == 0 {
+		return logger.NewLevelSet(logger.TRACE)
+	}
+	return stream.FilterLevels
+}
+
+func (stream *RecordingStream) Write(record *logger.Record) error {
+	if stream.Gate != nil {
+		<-stream.Gate
+	}
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.records = append(stream.records, record.Clone())
+	return nil
+}
+
+func (stream *RecordingStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+func (stream *RecordingStream) ShouldWrite(level logger.Level, topic, scope string) bool {
+	return level.ShouldWrite(stream.GetFilterLevels().Get(topic, scope))
+}
+
+func (stream *RecordingStream) Flush() {
+}
+
+func (stream *RecordingStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.closed = true
+}
+
+func (stream *RecordingStream) Clone() logger.Streamer {
+	return &RecordingStream{Gate: stream.Gate, FilterLevels: stream.FilterLevels.Clone(), SourceInfo: stream.SourceInfo}
+}
+
+// Records gets a copy of the records written so far
+func (stream *RecordingStream) Records() []*logger.Record {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return append([]*logger.Record(nil), stream.records...)
+}
+
+// Messages gets the "msg" of the records written so far
+func (stream *RecordingStream) Messages() []string {
+	messages := []string{}
+	for _, record := range stream.Records() {
+		if message, ok := record.Get("msg").(string); ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Reset discards the records written so far
+func (stream *RecordingStream) Reset() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.records = nil
+}
+
+// IsClosed tells if the stream was closed
+func (stream *RecordingStream) IsClosed() bool {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return stream.closed
+}
+
 // BogusValue is a bogus value that fails to marshal
 type BogusValue struct {
 }
@@ -3,6 +3,8 @@ package logger
 import "time"
 
 // CloudWatchConverter is used to convert a Record for AWS CloudWatch
+//
+// The trace identifier written by Logger.WithContext is also written in the AWS X-Ray format (key: "xray_trace_id").
 type CloudWatchConverter struct {
 }
 
@@ -18,5 +20,9 @@ func (converter *CloudWatchConverter) Convert(record *Record) *Record {
 			record.Data["time"] = rtime.Format(time.RFC3339)
 		}
 	}
+	if traceID, ok := record.Get("trace_id").(string); ok && len(traceID) == 32 {
+		// X-Ray trace ids are: version-epoch(8 hex digits)-random(24 hex digits)
+		record.Data["xray_trace_id"] = "1-" + traceID[:8] + "-" + traceID[8:]
+	}
 	return record
 }
@@ -158,3 +158,32 @@ func (suite *ConverterSuite) TestCanConvertWithCloudWatchConverter() {
 	suite.Assert().Equal(ALWAYS.String(), record.Get("severity"))
 	suite.Assert().Equal(ALWAYS, record.Get("level"))
 }
+
+func (suite *ConverterSuite) TestCanConvertTraceContextWithStackDriverConverter() {
+	converter := &StackDriverConverter{ProjectID: "acme"}
+	record := converter.Convert(NewRecord().Set("level", INFO).Set("msg", "Hello World!").
+		Set("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736").
+		Set("span_id", "00f067aa0ba902b7").
+		Set("trace_flags", "01"))
+	suite.Assert().Equal("projects/acme/traces/4bf92f3577b34da6a3ce929d0e0e4736", record.Get("logging.googleapis.com/trace"))
+	suite.Assert().Equal("00f067aa0ba902b7", record.Get("logging.googleapis.com/spanId"))
+	suite.Assert().Equal(true, record.Get("logging.googleapis.com/trace_sampled"))
+	suite.Assert().NotContains(record.Data, "trace_id")
+	suite.Assert().NotContains(record.Data, "span_id")
+	suite.Assert().NotContains(record.Data, "trace_flags")
+
+	record = converter.Convert(NewRecord().Set("msg", "Hello World!").Set("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736").Set("trace_flags", "00"))
+	suite.Assert().Equal(false, record.Get("logging.googleapis.com/trace_sampled"))
+
+	record = converter.Convert(NewRecord().Set("msg", "Hello World!"))
+	suite.Assert().NotContains(record.Data, "logging.googleapis.com/trace")
+	suite.Assert().NotContains(record.Data, "logging.googleapis.com/spanId")
+	suite.Assert().NotContains(record.Data, "logging.googleapis.com/trace_sampled")
+}
+
+func (suite *ConverterSuite) TestCanConvertTraceContextWithCloudWatchConverter() {
+	converter := &CloudWatchConverter{}
+	record := converter.Convert(NewRecord().Set("level", INFO).Set("msg", "Hello World!").Set("trace_id", "5759e988bd862e3fe1be46a994272793"))
+	suite.Assert().Equal("1-5759e988-bd862e3fe1be46a994272793", record.Get("xray_trace_id"))
+	suite.Assert().Equal("5759e988bd862e3fe1be46a994272793", record.Get("trace_id"))
+}
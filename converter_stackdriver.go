@@ -1,13 +1,19 @@
 package logger
 
 import (
+	"os"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/logging"
 )
 
 // StackDriverConverter is used to convert a Record for StackDriver
+//
+// The trace context written by Logger.WithContext is converted to the trace fields of StackDriver.
+// ProjectID is used to build the trace resource name (default: $GOOGLE_PROJECT_ID).
 type StackDriverConverter struct {
+	ProjectID string
 }
 
 // Convert converts the Record into a StackDriver compatible Record
@@ -21,9 +27,36 @@ func (converter *StackDriverConverter) Convert(record *Record) *Record {
 		}
 	}
 	record.Delete("msg")
+	converter.convertTrace(record)
 	return record
 }
 
+// convertTrace converts the trace context records into the StackDriver trace fields
+func (converter StackDriverConverter) convertTrace(record *Record) {
+	if traceID, ok := record.Get("trace_id").(string); ok && len(traceID) > 0 {
+		projectID := converter.ProjectID
+		if len(projectID) == 0 {
+			projectID = os.Getenv("GOOGLE_PROJECT_ID")
+		}
+		if len(projectID) > 0 {
+			record.Data["logging.googleapis.com/trace"] = "projects/" + projectID + "/traces/" + traceID
+		} else {
+			record.Data["logging.googleapis.com/trace"] = traceID
+		}
+		record.Delete("trace_id")
+	}
+	if spanID, ok := record.Get("span_id").(string); ok && len(spanID) > 0 {
+		record.Data["logging.googleapis.com/spanId"] = spanID
+		record.Delete("span_id")
+	}
+	if flags, ok := record.Get("trace_flags").(string); ok {
+		if value, err := strconv.ParseUint(flags, 16, 8); err == nil {
+			record.Data["logging.googleapis.com/trace_sampled"] = value&0x01 == 0x01
+		}
+		record.Delete("trace_flags")
+	}
+}
+
 func (converter StackDriverConverter) severity(value any) logging.Severity {
 	switch level := value.(type) {
 	case Level:
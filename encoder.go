@@ -0,0 +1,36 @@
+package logger
+
+// Encoder is used to encode a Record before it is written by a Stream
+//
+// The encoded payload must contain its own terminator (e.g.: a newline)
+type Encoder interface {
+	Encode(record *Record) ([]byte, error)
+}
+
+// EncoderFunc is an adapter to use an ordinary func as an Encoder
+type EncoderFunc func(record *Record) ([]byte, error)
+
+// Encode encodes the given Record
+//
+// implements logger.Encoder
+func (encode EncoderFunc) Encode(record *Record) ([]byte, error) {
+	return encode(record)
+}
+
+// JSONEncoder encodes Records as JSON objects, one per line
+//
+// It is used by StdoutStream, StderrStream, FileStream, and, by default, by WriterStream.
+type JSONEncoder struct{}
+
+// Encode encodes the given Record
+//
+// implements logger.Encoder
+func (encoder JSONEncoder) Encode(record *Record) ([]byte, error) {
+	payload, err := record.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	// payload might share its memory with a pooled buffer, so we do not append to it
+	encoded := make([]byte, 0, len(payload)+1)
+	return append(append(encoded, payload...), '\n'), nil
+}
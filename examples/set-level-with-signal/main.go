@@ -37,42 +37,14 @@ func generateSomeLogs(logger *logger.Logger) (stop chan struct{}) {
 	return stop
 }
 
-func handleSignals(logger *logger.Logger) (stopChannel chan struct{}) {
-	stopChannel = make(chan struct{}, 1)
-	signalChannel := make(chan os.Signal, 1)
-	signal.Notify(signalChannel, unix.SIGUSR1, unix.SIGUSR2)
-
-	go func() {
-		for {
-			select {
-			case sig := <-signalChannel:
-				switch sig {
-				case unix.SIGUSR1:
-					logger.Warnf("*********** Received SIGUSR1 -> Logging less stuff")
-					logger.FilterMore()
-					logger.Warnf("%s", logger)
-				case unix.SIGUSR2:
-					logger.Warnf("*********** Received SIGUSR2 -> Logging more stuff")
-					logger.FilterLess()
-					logger.Warnf("Logger: %s", logger)
-				default:
-					logger.Errorf("Received unsupported %s", sig)
-				}
-			case <-stopChannel:
-				logger.Warnf("Signals are not processed anymore")
-				return
-			}
-		}
-	}()
-	return stopChannel
-}
-
 func main() {
-	logger := logger.Create("signal", &logger.StdoutStream{Unbuffered: true})
+	log := logger.Create("signal", &logger.StdoutStream{Unbuffered: true})
 
-	logger.Infof("%s", logger)
-	stopHandlingChannel := handleSignals(logger)
-	stopGeneratingChannel := generateSomeLogs(logger)
+	log.Infof("%s", log)
+	ctx, stopHandling := context.WithCancel(context.Background())
+	// SIGUSR1 logs less stuff, SIGUSR2 logs more stuff
+	log.HandleLevelSignals(ctx, logger.LevelSignalOptions{})
+	stopGeneratingChannel := generateSomeLogs(log)
 
 	interruptChannel := make(chan os.Signal, 1)
 	exitChannel := make(chan struct{})
@@ -83,9 +55,9 @@ func main() {
 		_, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 
-		logger.Infof("Stopping after receiving %s", sig)
+		log.Infof("Stopping after receiving %s", sig)
 		close(stopGeneratingChannel)
-		close(stopHandlingChannel)
+		stopHandling()
 
 		close(exitChannel)
 	}()
@@ -0,0 +1,108 @@
+package grpclogger
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/gildas/go-logger"
+)
+
+// UnaryClientInterceptor gets a gRPC interceptor that logs the unary calls sent by a client
+//
+// The calls are logged with the Logger found in their context (for example, the one stored by a server interceptor
+// or by Logger.HttpHandler), or with the given Logger.
+// If there is no Logger in the context and the given Logger is nil, the calls are not logged.
+//
+// The request id of the context is sent in the metadata, a new one is generated if there is none.
+//
+// If present, the first parameter is the metadata key of the request id (default: "x-request-id").
+func UnaryClientInterceptor(log *logger.Logger, header ...string) grpc.UnaryClientInterceptor {
+	log = logger.CreateIfNil(log, "grpc")
+	key := requestIDHeader(header)
+	return func(ctx context.Context, fullMethod string, request, reply any, conn *grpc.ClientConn, invoker grpc.UnaryInvoker, options ...grpc.CallOption) error {
+		ctx, call := startClientCall(ctx, log, key, fullMethod, conn.Target(), "unary")
+		err := invoker(ctx, fullMethod, request, reply, conn, options...)
+		call.finish(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor gets a gRPC interceptor that logs the streaming calls sent by a client
+//
+// The calls are logged like UnaryClientInterceptor does.
+// The finish of a call is logged when the stream returns an error or io.EOF when receiving a message,
+// or when the single response of a client-streaming call is received.
+//
+// If present, the first parameter is the metadata key of the request id (default: "x-request-id").
+func StreamClientInterceptor(log *logger.Logger, header ...string) grpc.StreamClientInterceptor {
+	log = logger.CreateIfNil(log, "grpc")
+	key := requestIDHeader(header)
+	return func(ctx context.Context, desc *grpc.StreamDesc, conn *grpc.ClientConn, fullMethod string, streamer grpc.Streamer, options ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, call := startClientCall(ctx, log, key, fullMethod, conn.Target(), streamType(desc.ClientStreams, desc.ServerStreams))
+		stream, err := streamer(ctx, desc, conn, fullMethod, options...)
+		if err != nil {
+			call.finish(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: stream, call: call, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// startClientCall creates the child Logger of a sent call, logs the start of the call,
+// and sends the request id in the metadata
+func startClientCall(ctx context.Context, log *logger.Logger, key, fullMethod, target, callType string) (context.Context, call) {
+	start := time.Now()
+
+	var reqid string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			reqid = values[0]
+		}
+	}
+	if len(reqid) == 0 {
+		if value, ok := ctx.Value("reqid").(string); ok && len(value) > 0 {
+			reqid = value
+		} else {
+			reqid = newRequestID()
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, key, reqid)
+	}
+
+	service, method := splitMethod(fullMethod)
+	callLogger := logger.Must(logger.FromContext(ctx, log)).Child("grpc_client", fullMethod, "reqid", reqid, "grpc_service", service, "grpc_method", method, "target", target)
+	callLogger.Record("grpc_type", callType).Infof("⏳ call start: %s", fullMethod)
+	return ctx, call{logger: callLogger, method: fullMethod, start: start}
+}
+
+// clientStream is a grpc.ClientStream that logs the finish of the call
+type clientStream struct {
+	grpc.ClientStream
+	call          call
+	serverStreams bool
+	once          sync.Once
+}
+
+// RecvMsg receives a message from the stream
+//
+// When the stream ends, the finish of the call is logged.
+// If the server does not stream, the call ends with the first message.
+//
+// implements grpc.ClientStream
+func (stream *clientStream) RecvMsg(message any) error {
+	err := stream.ClientStream.RecvMsg(message)
+	if err != nil || !stream.serverStreams {
+		stream.once.Do(func() {
+			if err == io.EOF {
+				stream.call.finish(nil)
+			} else {
+				stream.call.finish(err)
+			}
+		})
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+// Package grpclogger provides gRPC interceptors that log the calls with github.com/gildas/go-logger
+//
+// Like Logger.HttpHandler does for HTTP requests, the server interceptors create a child Logger per call
+// with the method, the peer, and the request id found in the metadata (or a new one),
+// log the start and the finish of the call with its duration and status code,
+// and store the child Logger in the context of the call so logger.FromContext works in the handlers:
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpclogger.UnaryServerInterceptor(log)),
+//		grpc.ChainStreamInterceptor(grpclogger.StreamServerInterceptor(log)),
+//	)
+//
+// The client interceptors log the outgoing calls the same way, with the Logger found in the context of the call
+// (or the given Logger), and send the request id in the metadata so calls can be correlated across services:
+//
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithChainUnaryInterceptor(grpclogger.UnaryClientInterceptor(log)),
+//		grpc.WithChainStreamInterceptor(grpclogger.StreamClientInterceptor(log)),
+//	)
+//
+// All interceptors accept an optional metadata key for the request id (default: "x-request-id").
+package grpclogger
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/status"
+
+	"github.com/gildas/go-logger"
+)
+
+// DefaultRequestIDHeader is the metadata key used for the request id, the gRPC version of the X-Request-Id HTTP header
+const DefaultRequestIDHeader = "x-request-id"
+
+// call holds the logging information of a gRPC call
+type call struct {
+	logger *logger.Logger
+	method string
+	start  time.Time
+}
+
+// finish logs the end of the call
+//
+// The call is logged at ERROR if it failed, at INFO otherwise.
+func (call call) finish(err error) {
+	duration := time.Since(call.start)
+	log := call.logger.Records(
+		"duration", duration.Seconds(),
+		"grpc_code", status.Code(err).String(),
+	)
+	if err != nil {
+		log.Errorf("❌ call finish: %s in %s", call.method, duration, err)
+	} else {
+		log.Infof("✅ call finish: %s in %s", call.method, duration)
+	}
+}
+
+// requestIDHeader gets the metadata key of the request id from the optional parameters
+func requestIDHeader(headers []string) string {
+	if len(headers) > 0 && len(headers[0]) > 0 {
+		return strings.ToLower(headers[0]) // gRPC metadata keys are lowercase
+	}
+	return DefaultRequestIDHeader
+}
+
+// newRequestID generates a new request id
+func newRequestID() string {
+	return uuid.Must(uuid.NewRandom()).String()
+}
+
+// splitMethod splits a full gRPC method name (/package.Service/Method) into its service and method
+func splitMethod(fullMethod string) (service, method string) {
+	service, method, _ = strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return
+}
+
+// streamType gets the type of a streaming call
+func streamType(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return "bidi_stream"
+	case clientStreams:
+		return "client_stream"
+	case serverStreams:
+		return "server_stream"
+	default:
+		return "unary"
+	}
+}
+
+// withRequestID stores the request id in the context, like Logger.HttpHandler does
+func withRequestID(ctx context.Context, reqid string) context.Context {
+	//nolint:staticcheck
+	return context.WithValue(ctx, "reqid", reqid)
+}
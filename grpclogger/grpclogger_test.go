@@ -0,0 +1,299 @@
+package grpclogger_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-logger/grpclogger"
+	"github.com/gildas/go-logger/logtest"
+)
+
+type GRPCLoggerSuite struct {
+	suite.Suite
+	Name     string
+	Logger   *logger.Logger
+	Stream   *logtest.CaptureStream
+	Listener *bufconn.Listener
+	Server   *grpc.Server
+	Client   *grpc.ClientConn
+}
+
+func TestGRPCLoggerSuite(t *testing.T) {
+	suite.Run(t, new(GRPCLoggerSuite))
+}
+
+// echoServer is a hand-written gRPC service, so the tests do not need generated code
+//
+// Ping returns the request (or fails if the request is "fail"),
+// Watch sends the request 3 times,
+// Collect returns the requests it received, joined with commas.
+type echoServer struct{}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler: func(server any, ctx context.Context, decode func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				request := &wrapperspb.StringValue{}
+				if err := decode(request); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, request any) (any, error) {
+					value := request.(*wrapperspb.StringValue).GetValue()
+					logger.Must(logger.FromContext(ctx)).Infof("Ping %s", value)
+					if value == "fail" {
+						return nil, status.Error(codes.InvalidArgument, "Ping failed")
+					}
+					return wrapperspb.String(value), nil
+				}
+				if interceptor == nil {
+					return handler(ctx, request)
+				}
+				return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: server, FullMethod: "/test.Echo/Ping"}, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(server any, stream grpc.ServerStream) error {
+				request := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				log := logger.Must(logger.FromContext(stream.Context()))
+				for range 3 {
+					log.Infof("Watch %s", request.GetValue())
+					if err := stream.SendMsg(request); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			StreamName:    "Collect",
+			ClientStreams: true,
+			Handler: func(server any, stream grpc.ServerStream) error {
+				values := []string{}
+				for {
+					request := &wrapperspb.StringValue{}
+					if err := stream.RecvMsg(request); err == io.EOF {
+						break
+					} else if err != nil {
+						return err
+					}
+					values = append(values, request.GetValue())
+				}
+				logger.Must(logger.FromContext(stream.Context())).Infof("Collected %d values", len(values))
+				return stream.SendMsg(wrapperspb.String(strings.Join(values, ",")))
+			},
+		},
+	},
+}
+
+func (suite *GRPCLoggerSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *GRPCLoggerSuite) SetupTest() {
+	suite.Stream = &logtest.CaptureStream{FilterLevels: logger.NewLevelSet(logger.TRACE)}
+	suite.Logger = logger.Create(suite.Name, suite.Stream)
+	suite.Listener = bufconn.Listen(1024 * 1024)
+	suite.Server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpclogger.UnaryServerInterceptor(suite.Logger)),
+		grpc.ChainStreamInterceptor(grpclogger.StreamServerInterceptor(suite.Logger)),
+	)
+	suite.Server.RegisterService(&echoServiceDesc, &echoServer{})
+	go func() { _ = suite.Server.Serve(suite.Listener) }()
+
+	client, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return suite.Listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(grpclogger.UnaryClientInterceptor(suite.Logger)),
+		grpc.WithChainStreamInterceptor(grpclogger.StreamClientInterceptor(suite.Logger)),
+	)
+	suite.Require().NoError(err)
+	suite.Client = client
+}
+
+func (suite *GRPCLoggerSuite) TearDownTest() {
+	_ = suite.Client.Close()
+	suite.Server.Stop()
+}
+
+func (suite *GRPCLoggerSuite) TestCanLogUnaryCalls() {
+	reply := &wrapperspb.StringValue{}
+	var header metadata.MD
+	err := suite.Client.Invoke(context.Background(), "/test.Echo/Ping", wrapperspb.String("hello"), reply, grpc.Header(&header))
+	suite.Require().NoError(err)
+	suite.Assert().Equal("hello", reply.GetValue())
+
+	suite.Assert().Equal([]string{
+		"⏳ call start: /test.Echo/Ping",
+		"Ping hello",
+	}, suite.Stream.Messages("grpc")[:2])
+	records := suite.Stream.Records("grpc")
+	suite.Require().Len(records, 3)
+	reqid := records[0].Get("reqid")
+	suite.Assert().NotEmpty(reqid)
+	suite.Assert().Equal(header.Get(grpclogger.DefaultRequestIDHeader), []string{reqid.(string)}, "The request id should be sent back in the header")
+	for _, record := range records {
+		suite.Assert().Equal("/test.Echo/Ping", record.Get("scope"))
+		suite.Assert().Equal(reqid, record.Get("reqid"), "Record %s should have the request id", record.Get("msg"))
+		suite.Assert().Equal("test.Echo", record.Get("grpc_service"))
+		suite.Assert().Equal("Ping", record.Get("grpc_method"))
+		suite.Assert().Equal("bufconn", record.Get("peer"))
+	}
+	suite.Assert().Equal("unary", records[0].Get("grpc_type"))
+	suite.Assert().True(strings.HasPrefix(records[2].Get("msg").(string), "✅ call finish: /test.Echo/Ping in "))
+	suite.Assert().Equal("OK", records[2].Get("grpc_code"))
+	suite.Assert().NotNil(records[2].Get("duration"))
+
+	clientRecords := suite.Stream.Records("grpc_client")
+	suite.Require().Len(clientRecords, 2)
+	suite.Assert().Equal(reqid, clientRecords[0].Get("reqid"), "The client should send its request id")
+	suite.Assert().Equal("passthrough:///bufnet", clientRecords[0].Get("target"))
+	suite.Assert().Equal("OK", clientRecords[1].Get("grpc_code"))
+}
+
+func (suite *GRPCLoggerSuite) TestCanLogFailedUnaryCalls() {
+	err := suite.Client.Invoke(context.Background(), "/test.Echo/Ping", wrapperspb.String("fail"), &wrapperspb.StringValue{})
+	suite.Require().Error(err)
+	suite.Assert().Equal(codes.InvalidArgument, status.Code(err))
+
+	suite.Stream.AssertLogged(suite.T(), logger.ERROR, `^❌ call finish: /test\.Echo/Ping in `, map[string]any{"topic": "grpc", "grpc_code": "InvalidArgument"})
+	suite.Stream.AssertLogged(suite.T(), logger.ERROR, `^❌ call finish: /test\.Echo/Ping in `, map[string]any{"topic": "grpc_client", "grpc_code": "InvalidArgument"})
+	suite.Stream.AssertNotLogged(suite.T(), logger.INFO, `call finish`, nil)
+}
+
+func (suite *GRPCLoggerSuite) TestCanUseRequestIDFromContext() {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "1234")
+	err := suite.Client.Invoke(ctx, "/test.Echo/Ping", wrapperspb.String("hello"), &wrapperspb.StringValue{})
+	suite.Require().NoError(err)
+	suite.Stream.AssertLogged(suite.T(), logger.INFO, `^Ping hello$`, map[string]any{"reqid": "1234"})
+
+	//nolint:staticcheck
+	ctx = context.WithValue(context.Background(), "reqid", "5678")
+	err = suite.Client.Invoke(ctx, "/test.Echo/Ping", wrapperspb.String("again"), &wrapperspb.StringValue{})
+	suite.Require().NoError(err)
+	suite.Stream.AssertLogged(suite.T(), logger.INFO, `^Ping again$`, map[string]any{"reqid": "5678"})
+}
+
+func (suite *GRPCLoggerSuite) TestCanUseLoggerFromContext() {
+	caller := suite.Logger.Child("caller", nil, "user", "john")
+	err := suite.Client.Invoke(caller.ToContext(context.Background()), "/test.Echo/Ping", wrapperspb.String("hello"), &wrapperspb.StringValue{})
+	suite.Require().NoError(err)
+	clientRecords := suite.Stream.Records("grpc_client")
+	suite.Require().Len(clientRecords, 2)
+	for _, record := range clientRecords {
+		suite.Assert().Equal("john", record.Get("user"), "The client should log with the Logger of the context")
+	}
+}
+
+func (suite *GRPCLoggerSuite) TestCanCallWithoutLogger() {
+	client, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return suite.Listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(grpclogger.UnaryClientInterceptor(nil)),
+		grpc.WithChainStreamInterceptor(grpclogger.StreamClientInterceptor(nil)),
+	)
+	suite.Require().NoError(err)
+	defer client.Close()
+
+	reply := &wrapperspb.StringValue{}
+	suite.Require().NotPanics(func() {
+		err = client.Invoke(context.Background(), "/test.Echo/Ping", wrapperspb.String("hello"), reply)
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal("hello", reply.GetValue())
+	suite.Assert().Empty(suite.Stream.Records("grpc_client"), "The client should not log without a Logger")
+	suite.Assert().Len(suite.Stream.Records("grpc"), 3, "The server should still log")
+}
+
+func (suite *GRPCLoggerSuite) TestCanLogStreamingCalls() {
+	stream, err := suite.Client.NewStream(context.Background(), &echoServiceDesc.Streams[0], "/test.Echo/Watch")
+	suite.Require().NoError(err)
+	suite.Require().NoError(stream.SendMsg(wrapperspb.String("hello")))
+	suite.Require().NoError(stream.CloseSend())
+	received := 0
+	for {
+		reply := &wrapperspb.StringValue{}
+		if err := stream.RecvMsg(reply); err == io.EOF {
+			break
+		} else {
+			suite.Require().NoError(err)
+		}
+		suite.Assert().Equal("hello", reply.GetValue())
+		received++
+	}
+	suite.Assert().Equal(3, received)
+
+	messages := suite.Stream.Messages("grpc")
+	suite.Require().Len(messages, 5)
+	suite.Assert().Equal("⏳ call start: /test.Echo/Watch", messages[0])
+	suite.Assert().Equal([]string{"Watch hello", "Watch hello", "Watch hello"}, messages[1:4])
+	suite.Assert().True(strings.HasPrefix(messages[4], "✅ call finish: /test.Echo/Watch in "))
+	suite.Assert().Equal("server_stream", suite.Stream.Records("grpc")[0].Get("grpc_type"))
+
+	clientRecords := suite.Stream.Records("grpc_client")
+	suite.Require().Len(clientRecords, 2, "The client should log the finish only once")
+	suite.Assert().Equal("server_stream", clientRecords[0].Get("grpc_type"))
+	suite.Assert().Equal("OK", clientRecords[1].Get("grpc_code"))
+}
+
+func (suite *GRPCLoggerSuite) TestCanLogClientStreamingCalls() {
+	stream, err := suite.Client.NewStream(context.Background(), &echoServiceDesc.Streams[1], "/test.Echo/Collect")
+	suite.Require().NoError(err)
+	for _, value := range []string{"a", "b", "c"} {
+		suite.Require().NoError(stream.SendMsg(wrapperspb.String(value)))
+	}
+	suite.Require().NoError(stream.CloseSend())
+	reply := &wrapperspb.StringValue{}
+	suite.Require().NoError(stream.RecvMsg(reply))
+	suite.Assert().Equal("a,b,c", reply.GetValue())
+
+	clientRecords := suite.Stream.Records("grpc_client")
+	suite.Require().Len(clientRecords, 2, "The client should log the finish without waiting for io.EOF")
+	suite.Assert().Equal("client_stream", clientRecords[0].Get("grpc_type"))
+	suite.Assert().Equal("OK", clientRecords[1].Get("grpc_code"))
+	suite.Assert().True(strings.HasPrefix(clientRecords[1].Get("msg").(string), "✅ call finish: /test.Echo/Collect in "))
+}
+
+func (suite *GRPCLoggerSuite) TestCanUseCustomRequestIDHeader() {
+	suite.Server.Stop()
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(grpclogger.UnaryServerInterceptor(suite.Logger, "X-Correlation-Id")))
+	server.RegisterService(&echoServiceDesc, &echoServer{})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	client, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	suite.Require().NoError(err)
+	defer client.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-correlation-id", "1234")
+	err = client.Invoke(ctx, "/test.Echo/Ping", wrapperspb.String("hello"), &wrapperspb.StringValue{})
+	suite.Require().NoError(err)
+	suite.Stream.AssertLogged(suite.T(), logger.INFO, `^Ping hello$`, map[string]any{"reqid": "1234"})
+}
@@ -0,0 +1,87 @@
+package grpclogger
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/gildas/go-logger"
+)
+
+// UnaryServerInterceptor gets a gRPC interceptor that logs the unary calls received by a server
+//
+// If the Logger is nil, the calls are not logged.
+//
+// If present, the first parameter is the metadata key of the request id (default: "x-request-id").
+func UnaryServerInterceptor(log *logger.Logger, header ...string) grpc.UnaryServerInterceptor {
+	log = logger.CreateIfNil(log, "grpc")
+	key := requestIDHeader(header)
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, call, reqid := startServerCall(ctx, log, key, info.FullMethod, "unary")
+		_ = grpc.SetHeader(ctx, metadata.Pairs(key, reqid))
+
+		response, err := handler(ctx, request)
+		call.finish(err)
+		return response, err
+	}
+}
+
+// StreamServerInterceptor gets a gRPC interceptor that logs the streaming calls received by a server
+//
+// If present, the first parameter is the metadata key of the request id (default: "x-request-id").
+func StreamServerInterceptor(log *logger.Logger, header ...string) grpc.StreamServerInterceptor {
+	log = logger.CreateIfNil(log, "grpc")
+	key := requestIDHeader(header)
+	return func(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, call, reqid := startServerCall(stream.Context(), log, key, info.FullMethod, streamType(info.IsClientStream, info.IsServerStream))
+		_ = stream.SetHeader(metadata.Pairs(key, reqid))
+
+		err := handler(server, &serverStream{ServerStream: stream, ctx: ctx})
+		call.finish(err)
+		return err
+	}
+}
+
+// startServerCall creates the child Logger of a received call, logs the start of the call,
+// and stores the Logger and the request id in the context
+func startServerCall(ctx context.Context, log *logger.Logger, key, fullMethod, callType string) (context.Context, call, string) {
+	start := time.Now()
+
+	var reqid string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			reqid = values[0]
+		}
+	}
+	if len(reqid) == 0 {
+		reqid = newRequestID()
+	}
+
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+
+	service, method := splitMethod(fullMethod)
+	callLogger := log.Child("grpc", fullMethod, "reqid", reqid, "grpc_service", service, "grpc_method", method, "peer", remote)
+	callLogger.Record("grpc_type", callType).Infof("⏳ call start: %s", fullMethod)
+
+	ctx = callLogger.ToContext(withRequestID(ctx, reqid))
+	return ctx, call{logger: callLogger, method: fullMethod, start: start}, reqid
+}
+
+// serverStream is a grpc.ServerStream that carries the context with the call's Logger
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context gets the context of the call
+//
+// implements grpc.ServerStream
+func (stream *serverStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package logger
+
+import (
+	"net"
+	"os"
+
+	"github.com/gildas/go-errors"
+	"golang.org/x/sys/unix"
+)
+
+// sendJournalPayloadWithFileDescriptor sends a payload too big for a datagram to journald
+//
+// The payload is written in a sealed memfd (or an unlinked file in /dev/shm) whose descriptor is sent to journald
+func sendJournalPayloadWithFileDescriptor(connection *net.UnixConn, payload []byte) (err error) {
+	var file *os.File
+
+	if fd, err := unix.MemfdCreate("go-logger-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING); err == nil {
+		file = os.NewFile(uintptr(fd), "go-logger-journal")
+	} else {
+		if file, err = os.CreateTemp("/dev/shm", "go-logger-journal-"); err != nil {
+			return errors.WithStack(err)
+		}
+		_ = os.Remove(file.Name())
+	}
+	defer file.Close()
+
+	if _, err = file.Write(payload); err != nil {
+		return errors.WithStack(err)
+	}
+	// journald requires memfds to be sealed, sealing fails on regular files and that is fine
+	_, _ = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL)
+	// net.UnixConn refuses WriteMsgUnix on connected datagram sockets, so we send the descriptor ourselves
+	rawConnection, err := connection.SyscallConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var sendErr error
+	err = rawConnection.Write(func(fd uintptr) bool {
+		sendErr = unix.Sendmsg(int(fd), nil, unix.UnixRights(int(file.Fd())), nil, 0)
+		return sendErr != unix.EAGAIN
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(sendErr)
+}
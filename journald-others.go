@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package logger
+
+import (
+	"net"
+
+	"github.com/gildas/go-errors"
+)
+
+// sendJournalPayloadWithFileDescriptor sends a payload too big for a datagram to journald
+//
+// journald runs only on Linux
+func sendJournalPayloadWithFileDescriptor(connection *net.UnixConn, payload []byte) error {
+	return errors.NotImplemented.WithStack()
+}
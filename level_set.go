@@ -121,6 +121,19 @@ func (set LevelSet) Get(topic, scope string) (level Level) {
 	return set[newTopicscope("any", "any")]
 }
 
+// lowered gets a copy of the LevelSet where no level is higher than the given level
+//
+// Levels that do not filter (UNSET, ALWAYS) and levels that filter everything (NEVER) are kept.
+func (set LevelSet) lowered(level Level) LevelSet {
+	lowered := set.Clone()
+	for ts, current := range lowered {
+		if current != NEVER && current != ALWAYS && current > level {
+			lowered[ts] = level
+		}
+	}
+	return lowered
+}
+
 // ShouldWrite Tells if the given Level, Topic, and Scope should be written
 func (set LevelSet) ShouldWrite(level Level, topic, scope string) bool {
 	return level.ShouldWrite(set.Get(topic, scope))
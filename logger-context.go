@@ -2,6 +2,8 @@ package logger
 
 import (
 	"context"
+	"sync"
+
 	"github.com/gildas/go-errors"
 )
 
@@ -10,6 +12,22 @@ type key int
 // contextKey is the key for logger child stored in Context
 const contextKey key = iota + 12583
 
+const (
+	// recordsContextKey is the key for the records stored in Context
+	recordsContextKey key = iota + 12584
+	// levelsContextKey is the key for the LevelSet stored in Context
+	levelsContextKey
+	// debugLevelContextKey is the key for the Level a request lowered its levels to (see HttpHandlerOptions.DebugHeader)
+	debugLevelContextKey
+)
+
+// contextKeys are the keys of the context values that Logger.Ctx writes, indexed by Record name
+var contextKeys = map[string]any{
+	"reqid": "reqid", // stored by HttpHandler
+}
+
+var contextKeysMutex sync.RWMutex
+
 // FromContext retrieves the Logger stored in the context
 //
 // Sources are either LoggerCarrier implemenations or Logger/*Logger objects.
@@ -39,3 +57,248 @@ func FromContext(context context.Context, sources ...any) (*Logger, error) {
 func (l *Logger) ToContext(parent context.Context) context.Context {
 	return context.WithValue(parent, contextKey, l)
 }
+
+// ContextWithRecords stores records in the given context
+//
+// The parameters are key/value pairs, like Logger.Records.
+// The records already stored in the context are kept, unless they have the same key.
+//
+// Logger.Ctx and the ...Ctx methods write these records.
+func ContextWithRecords(parent context.Context, params ...any) context.Context {
+	records := map[string]any{}
+	if stored, ok := parent.Value(recordsContextKey).(map[string]any); ok {
+		for key, value := range stored {
+			records[key] = value
+		}
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		if key, ok := params[i].(string); ok {
+			records[key] = params[i+1]
+		}
+	}
+	return context.WithValue(parent, recordsContextKey, records)
+}
+
+// ContextWithLevels stores a LevelSet in the given context
+//
+// Logger.Ctx and the ...Ctx methods filter with this LevelSet instead of the one of the Logger's streams.
+//
+// Example, to trace everything for one request:
+//
+//	ctx = logger.ContextWithLevels(ctx, logger.NewLevelSet(logger.TRACE))
+func ContextWithLevels(parent context.Context, levels LevelSet) context.Context {
+	return context.WithValue(parent, levelsContextKey, levels)
+}
+
+// LevelsFromContext retrieves the LevelSet stored in the context
+func LevelsFromContext(ctx context.Context) (LevelSet, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	levels, ok := ctx.Value(levelsContextKey).(LevelSet)
+	return levels, ok
+}
+
+// contextWithDebugLevel stores in the given context the Level the levels of the streams are lowered to
+func contextWithDebugLevel(parent context.Context, level Level) context.Context {
+	return context.WithValue(parent, debugLevelContextKey, level)
+}
+
+// debugLevelFromContext retrieves the Level stored with contextWithDebugLevel
+func debugLevelFromContext(ctx context.Context) (Level, bool) {
+	level, ok := ctx.Value(debugLevelContextKey).(Level)
+	return level, ok
+}
+
+// RegisterContextKey tells Logger.Ctx to write the value stored in the context with the given key
+//
+// The value is written as a Record with the given name.
+//
+// By default, the request identifier stored by HttpHandler is written as "reqid".
+//
+// If key is nil, the name is unregistered.
+func RegisterContextKey(name string, key any) {
+	contextKeysMutex.Lock()
+	defer contextKeysMutex.Unlock()
+	if key == nil {
+		delete(contextKeys, name)
+		return
+	}
+	contextKeys[name] = key
+}
+
+// Ctx gets a Logger that writes what the given context carries
+//
+// The returned Logger writes:
+//   - the trace context (see WithContext),
+//   - the values of the keys given to RegisterContextKey,
+//   - the records stored with ContextWithRecords.
+//
+// If a LevelSet was stored with ContextWithLevels, the returned Logger filters with it.
+//
+// Otherwise, if HttpHandler lowered the levels of the request (see HttpHandlerOptions.DebugHeader),
+// the returned Logger filters with the LevelSet of each of its streams lowered to that level.
+//
+// If the context carries nothing, the Logger itself is returned.
+func (log *Logger) Ctx(ctx context.Context) *Logger {
+	if ctx == nil {
+		return log
+	}
+	ctxLogger := log.WithContext(ctx)
+
+	records := map[string]any{}
+	contextKeysMutex.RLock()
+	for name, key := range contextKeys {
+		if value := ctx.Value(key); value != nil {
+			records[name] = value
+		}
+	}
+	contextKeysMutex.RUnlock()
+	if stored, ok := ctx.Value(recordsContextKey).(map[string]any); ok {
+		for key, value := range stored {
+			records[key] = value
+		}
+	}
+	if len(records) > 0 {
+		ctxLogger = ctxLogger.RecordMap(records)
+	}
+
+	if levels, ok := LevelsFromContext(ctx); ok {
+		ctxLogger = ctxLogger.withLevels(levels)
+	} else if level, ok := debugLevelFromContext(ctx); ok {
+		ctxLogger = ctxLogger.withLoweredLevels(level)
+	}
+	return ctxLogger
+}
+
+// withLevels gets a Logger that filters with the given LevelSets instead of the ones of its streams
+func (log *Logger) withLevels(levels ...LevelSet) *Logger {
+	return &Logger{log.environmentPrefix, &levelsStream{Streamer: log, levels: levels}, NewRecord(), log.obfuscationKey, log.redactors}
+}
+
+// withLoweredLevels gets a Logger that filters with the LevelSet of each of its streams lowered to the given level
+func (log *Logger) withLoweredLevels(level Level) *Logger {
+	levels := []LevelSet{}
+	for _, stream := range leafStreams(log) {
+		levels = append(levels, stream.GetFilterLevels().lowered(level))
+	}
+	return log.withLevels(levels...)
+}
+
+// TracefCtx traces a message at the TRACE Level with what the given context carries (see Ctx)
+func (log *Logger) TracefCtx(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).send(TRACE, msg, args...)
+}
+
+// DebugfCtx traces a message at the DEBUG Level with what the given context carries (see Ctx)
+func (log *Logger) DebugfCtx(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).send(DEBUG, msg, args...)
+}
+
+// InfofCtx traces a message at the INFO Level with what the given context carries (see Ctx)
+func (log *Logger) InfofCtx(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).send(INFO, msg, args...)
+}
+
+// WarnfCtx traces a message at the WARN Level with what the given context carries (see Ctx)
+func (log *Logger) WarnfCtx(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).send(WARN, msg, args...)
+}
+
+// ErrorfCtx traces a message at the ERROR Level with what the given context carries (see Ctx)
+//
+// If the last argument is an error, a Record is added and the error string is added to the message
+func (log *Logger) ErrorfCtx(ctx context.Context, msg string, args ...any) {
+	logWithErr, msg, args := log.Ctx(ctx).withError(msg, args)
+	logWithErr.send(ERROR, msg, args...)
+}
+
+// FatalfCtx traces a message at the FATAL Level with what the given context carries (see Ctx)
+//
+// If the last argument is an error, a Record is added and the error string is added to the message
+func (log *Logger) FatalfCtx(ctx context.Context, msg string, args ...any) {
+	logWithErr, msg, args := log.Ctx(ctx).withError(msg, args)
+	logWithErr.send(FATAL, msg, args...)
+}
+
+// levelsStream is a Stream that filters with its own LevelSets before writing to another Stream
+//
+// It is used by Logger.Ctx to change the levels for a context without changing the Streams.
+//
+// Like MultiStream, a record is written if one of the LevelSets allows it.
+type levelsStream struct {
+	Streamer
+	levels []LevelSet
+}
+
+// GetFilterLevels gets the filter levels
+//
+// If there are several LevelSets, the first one is returned.
+//
+// implements logger.Streamer
+func (stream *levelsStream) GetFilterLevels() LevelSet {
+	if len(stream.levels) == 0 {
+		return LevelSet{}
+	}
+	return stream.levels[0]
+}
+
+// SetFilterLevel sets the filter level of the wrapped stream
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *levelsStream) SetFilterLevel(level Level, parameters ...string) {
+	if setter, ok := stream.Streamer.(FilterSetter); ok {
+		setter.SetFilterLevel(level, parameters...)
+	}
+}
+
+// FilterMore tells the wrapped stream to filter more
+//
+// implements logger.FilterModifier
+func (stream *levelsStream) FilterMore() {
+	if modifier, ok := stream.Streamer.(FilterModifier); ok {
+		modifier.FilterMore()
+	}
+}
+
+// FilterLess tells the wrapped stream to filter less
+//
+// implements logger.FilterModifier
+func (stream *levelsStream) FilterLess() {
+	if modifier, ok := stream.Streamer.(FilterModifier); ok {
+		modifier.FilterLess()
+	}
+}
+
+// Reopen reopens the wrapped stream if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (stream *levelsStream) Reopen() error {
+	if reopener, ok := stream.Streamer.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *levelsStream) ShouldWrite(level Level, topic, scope string) bool {
+	for _, levels := range stream.levels {
+		if levels.ShouldWrite(level, topic, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a copy of the stream
+//
+// implements logger.Streamer
+func (stream *levelsStream) Clone() Streamer {
+	return &levelsStream{Streamer: stream.Streamer.Clone(), levels: stream.levels}
+}
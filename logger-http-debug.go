@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignDebugLevel gets a signed value for the debug header of HttpHandlerOptions
+//
+// The value lowers the levels of the requests that carry it until it expires.
+//
+// Example, to trace a customer's requests for the next hour:
+//
+//	value := logger.SignDebugLevel(secret, logger.TRACE, time.Now().Add(time.Hour))
+//	// The customer sends: X-Debug-Log: trace;exp=1700000000;sig=...
+func SignDebugLevel(secret []byte, level Level, expires time.Time) string {
+	payload := fmt.Sprintf("%s;exp=%d", strings.ToLower(level.String()), expires.Unix())
+	return payload + ";sig=" + signDebugPayload(secret, payload)
+}
+
+// signDebugPayload signs the payload of a debug header value
+func signDebugPayload(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// debugLevel gets the level a request asks for in its debug header, if it is allowed to
+//
+// The value of the header is allowed if it is signed with DebugSecret and has not expired,
+// or if DebugAllowed allows the request.
+func (options HttpHandlerOptions) debugLevel(r *http.Request) (Level, bool) {
+	if len(options.DebugSecret) == 0 && options.DebugAllowed == nil {
+		return UNSET, false
+	}
+	header := options.DebugHeader
+	if len(header) == 0 {
+		header = "X-Debug-Log"
+	}
+	value := strings.TrimSpace(r.Header.Get(header))
+	if len(value) == 0 {
+		return UNSET, false
+	}
+
+	if len(options.DebugSecret) > 0 {
+		if payload, signature, found := strings.Cut(value, ";sig="); found {
+			if hmac.Equal([]byte(signature), []byte(signDebugPayload(options.DebugSecret, payload))) {
+				name, expires, _ := strings.Cut(payload, ";exp=")
+				if timestamp, err := strconv.ParseInt(expires, 10, 64); err == nil && time.Now().Before(time.Unix(timestamp, 0)) {
+					return validDebugLevel(name)
+				}
+			}
+		}
+	}
+	if options.DebugAllowed != nil && options.DebugAllowed(r) {
+		name, _, _ := strings.Cut(value, ";")
+		return validDebugLevel(name)
+	}
+	return UNSET, false
+}
+
+// validDebugLevel parses a level given in a debug header
+func validDebugLevel(value string) (Level, bool) {
+	level := ParseLevel(strings.TrimSpace(value))
+	if level == NEVER || level == UNSET {
+		return UNSET, false
+	}
+	return level, true
+}
@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// levelHandler is an http.Handler that shows and changes the levels of the streams of a Logger
+type levelHandler struct {
+	logger *Logger
+	mutex  sync.Mutex
+	// snapshot holds the levels of the streams before the changes that will be reverted
+	snapshot []streamLevels
+	// changed holds the topic/scope pairs changed since the snapshot
+	changed LevelSet
+	expires time.Time
+	timer   *time.Timer
+	// generation identifies the latest change, so an older timer does not revert it
+	generation uint64
+}
+
+// streamLevels is the LevelSet of a Stream
+type streamLevels struct {
+	stream Streamer
+	levels LevelSet
+}
+
+// levelsState is the JSON representation of the levels of the streams of a Logger
+type levelsState struct {
+	Streams []streamState `json:"streams"`
+	Expires *time.Time    `json:"expires,omitempty"`
+}
+
+type streamState struct {
+	Stream string `json:"stream"`
+	Levels string `json:"levels"`
+}
+
+// LevelHandler gets an http.Handler to show and change the levels of the Streams of this Logger at runtime
+//
+// GET returns the LevelSet of every Stream as JSON.
+//
+// PUT and POST set the levels given in the request body, with the same syntax as LOG_LEVEL (see ParseLevels).
+// The levels are set for the topics/scopes of the body, the other levels of the Streams are kept.
+//
+// If the "ttl" query parameter is given (e.g.: "10m"), the changes are reverted after that duration.
+// A change without "ttl" made before the changes are reverted makes them permanent.
+//
+// This handler should be served on an admin port or behind an authorization middleware.
+//
+// Example:
+//
+//	mux.Handle("/debug/log/levels", log.LevelHandler())
+//	// curl -X PUT --data 'INFO;TRACE:{db}' 'http://localhost:8081/debug/log/levels?ttl=15m'
+func (l *Logger) LevelHandler() http.Handler {
+	return &levelHandler{logger: l}
+}
+
+// ServeHTTP shows or changes the levels of the streams
+//
+// implements http.Handler
+func (handler *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		handler.writeState(w)
+	case http.MethodPut, http.MethodPost:
+		var ttl time.Duration
+		if value := r.URL.Query().Get("ttl"); len(value) > 0 {
+			var err error
+			if ttl, err = time.ParseDuration(value); err != nil || ttl <= 0 {
+				http.Error(w, errors.ArgumentInvalid.With("ttl", value).Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+		if err != nil {
+			http.Error(w, "Failed to read the levels", http.StatusBadRequest)
+			return
+		}
+		levels, err := parseLevelsStrictly(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler.setLevels(levels, ttl)
+		handler.writeState(w)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// setLevels sets the given levels on the streams and schedules their revert if ttl is not 0
+func (handler *levelHandler) setLevels(levels LevelSet, ttl time.Duration) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	pending := handler.timer != nil && handler.timer.Stop()
+	if !pending {
+		handler.snapshot = []streamLevels{}
+		for _, stream := range leafStreams(handler.logger) {
+			handler.snapshot = append(handler.snapshot, streamLevels{stream: stream, levels: stream.GetFilterLevels().Clone()})
+		}
+		handler.changed = LevelSet{}
+	}
+	for topicscope, level := range levels {
+		handler.logger.SetFilterLevel(level, topicscope.Topic, topicscope.Scope)
+		handler.changed[topicscope] = level
+	}
+	handler.generation++
+	handler.timer = nil
+	handler.expires = time.Time{}
+
+	if ttl > 0 {
+		generation := handler.generation
+		handler.expires = time.Now().Add(ttl)
+		handler.timer = time.AfterFunc(ttl, func() { handler.revert(generation) })
+		handler.logger.Warnf("Log levels set to %s for %s", levels, ttl)
+	} else {
+		handler.snapshot = nil
+		handler.changed = nil
+		handler.logger.Warnf("Log levels set to %s", levels)
+	}
+}
+
+// revert restores the levels the streams had before the changes
+//
+// If another change was made since the timer was started, nothing is reverted.
+func (handler *levelHandler) revert(generation uint64) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if generation != handler.generation || handler.snapshot == nil {
+		return
+	}
+	for _, snapshot := range handler.snapshot {
+		if setter, ok := snapshot.stream.(FilterSetter); ok {
+			for topicscope := range handler.changed {
+				if level, found := snapshot.levels[topicscope]; found {
+					setter.SetFilterLevel(level, topicscope.Topic, topicscope.Scope)
+				} else {
+					// The topic/scope pair was not set before, so it falls back again to its topic, its scope, or the default level
+					delete(snapshot.stream.GetFilterLevels(), topicscope)
+				}
+			}
+		}
+	}
+	handler.snapshot = nil
+	handler.changed = nil
+	handler.timer = nil
+	handler.expires = time.Time{}
+	handler.logger.Warnf("Log levels reverted")
+}
+
+// writeState writes the levels of the streams as JSON
+func (handler *levelHandler) writeState(w http.ResponseWriter) {
+	handler.mutex.Lock()
+	state := levelsState{Streams: []streamState{}}
+	for _, stream := range leafStreams(handler.logger) {
+		state.Streams = append(state.Streams, streamState{Stream: fmt.Sprintf("%s", stream), Levels: stream.GetFilterLevels().Clone().String()})
+	}
+	if !handler.expires.IsZero() {
+		expires := handler.expires.UTC()
+		state.Expires = &expires
+	}
+	handler.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// parseLevelsStrictly parses levels like ParseLevels, but fails on unknown levels and empty settings
+//
+// ParseLevels uses NEVER for unknown levels, which would silence the streams on a typo.
+func parseLevelsStrictly(settings string) (LevelSet, error) {
+	settings = strings.TrimSpace(settings)
+	if len(settings) == 0 {
+		return nil, errors.ArgumentMissing.With("levels")
+	}
+	for _, setting := range strings.Split(settings, ";") {
+		name, _, _ := strings.Cut(strings.TrimSpace(setting), ":")
+		if len(name) > 0 && ParseLevel(name) == NEVER && !strings.EqualFold(name, "NEVER") {
+			return nil, errors.ArgumentInvalid.With("level", name)
+		}
+	}
+	levels := ParseLevels(settings)
+	if len(levels) == 0 {
+		return nil, errors.ArgumentInvalid.With("levels", settings)
+	}
+	return levels, nil
+}
+
+// leafStreams gets the Streams that hold the levels of the given Stream
+//
+// Loggers, MultiStreams, and the Streams that write to a Destination are walked through.
+func leafStreams(stream Streamer) []Streamer {
+	switch actual := stream.(type) {
+	case *Logger:
+		return leafStreams(actual.stream)
+	case *levelsStream:
+		return leafStreams(actual.Streamer)
+	case *MultiStream:
+		streams := []Streamer{}
+		for _, s := range actual.streams {
+			streams = append(streams, leafStreams(s)...)
+		}
+		return streams
+	case *AsyncStream:
+		return leafStreams(actual.Destination)
+	case *SamplingStream:
+		return leafStreams(actual.Destination)
+	case *DedupStream:
+		return leafStreams(actual.Destination)
+	case *FingersCrossedStream:
+		return leafStreams(actual.Destination)
+	default:
+		return []Streamer{stream}
+	}
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// httpTransport is an http.RoundTripper that logs the outbound requests
+type httpTransport struct {
+	logger *Logger
+	next   http.RoundTripper
+	header string
+}
+
+// HttpTransport function will wrap an http.RoundTripper with extra logging information
+//
+// If next is nil, http.DefaultTransport is used.
+//
+// Example:
+//
+//	client := &http.Client{Transport: log.HttpTransport(http.DefaultTransport)}
+func (l *Logger) HttpTransport(next http.RoundTripper) http.RoundTripper {
+	return l.HttpTransportWithRequestIDHeader(next, "X-Request-Id")
+}
+
+// HttpTransportWithRequestIDHeader function will wrap an http.RoundTripper with extra logging information
+//
+// It allows to specify the header field to use for request ID.
+//
+// The requests are logged with the Logger found in their context (for example, the one stored by HttpHandler),
+// or with this Logger.
+//
+// The request ID is taken from the request header, from the request context (as stored by HttpHandler),
+// or generated, and is sent in the header so the calls can be correlated across services.
+//
+// The W3C trace context of the request context (see Logger.WithContext) is sent in the traceparent and tracestate headers,
+// unless the request already has them.
+//
+// The values of the URL query parameters and the URL password are redacted.
+//
+// The finish of a request is logged when the response body is closed, with the number of bytes that were read from it.
+func (l *Logger) HttpTransportWithRequestIDHeader(next http.RoundTripper, header string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &httpTransport{logger: l, next: next, header: header}
+}
+
+// RoundTrip executes a single HTTP transaction and logs it
+//
+// implements http.RoundTripper
+func (transport *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log, err := FromContext(req.Context(), transport.logger)
+	if err != nil {
+		return transport.next.RoundTrip(req)
+	}
+	start := time.Now()
+
+	// Get a request identifier and send it with the request
+	var reqid string
+	var sendRequestID bool
+
+	if len(transport.header) > 0 {
+		reqid = req.Header.Get(transport.header)
+		if len(reqid) == 0 {
+			if value, ok := req.Context().Value("reqid").(string); ok && len(value) > 0 {
+				reqid = value
+			} else {
+				reqid = uuid.Must(uuid.NewRandom()).String()
+			}
+			sendRequestID = true
+		}
+	}
+
+	// Propagate the W3C trace context of the request context, if the request does not have one
+	sendTraceContext := len(req.Header.Get("traceparent")) == 0 && trace.SpanContextFromContext(req.Context()).IsValid()
+
+	if sendRequestID || sendTraceContext {
+		// A RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		if sendRequestID {
+			req.Header.Set(transport.header, reqid)
+		}
+		if sendTraceContext {
+			propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+		}
+	}
+
+	location := redactURL(req.URL)
+	reqLogger := log.WithContext(req.Context()).Child("http_client", req.URL.Path, "reqid", reqid, "url", location, "verb", req.Method)
+	reqLogger.Infof("⏳ request start: %s %s", req.Method, location)
+
+	res, err := transport.next.RoundTrip(req)
+
+	finishLogger := reqLogger
+	if req.ContentLength >= 0 {
+		finishLogger = finishLogger.Record("sent", req.ContentLength)
+	}
+	if err != nil {
+		duration := time.Since(start)
+		finishLogger = finishLogger.Record("duration", duration.Seconds())
+		logError := err
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			// url.Error contains the full URL
+			logError = &url.Error{Op: urlError.Op, URL: location, Err: urlError.Err}
+		}
+		finishLogger.Errorf("❌ request failed: %s %s in %s", req.Method, location, duration, logError)
+		return res, err
+	}
+	finishLogger = finishLogger.Record("http_status", res.StatusCode)
+	finish := func(received int64) {
+		// Logging the duration of the request, including the time to read the response
+		duration := time.Since(start)
+		finishLogger := finishLogger.Record("duration", duration.Seconds())
+		if received >= 0 {
+			finishLogger = finishLogger.Record("received", received)
+		}
+		if res.StatusCode >= 400 {
+			finishLogger.Errorf("❌ request finish: %s %s in %s", req.Method, location, duration)
+		} else {
+			finishLogger.Infof("✅ request finish: %s %s in %s", req.Method, location, duration)
+		}
+	}
+	if res.Body == nil || res.StatusCode == http.StatusSwitchingProtocols {
+		// The body of a protocol switch is the connection, it must stay an io.ReadWriteCloser
+		finish(res.ContentLength)
+		return res, nil
+	}
+	res.Body = &countingBody{ReadCloser: res.Body, finish: finish}
+	return res, nil
+}
+
+// countingBody is the body of a response that counts the bytes read from it and logs the finish of the request when closed
+type countingBody struct {
+	io.ReadCloser
+	received int64
+	finish   func(received int64)
+	once     sync.Once
+}
+
+// Read reads from the body and counts the bytes
+//
+// implements io.Reader
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.received += int64(n)
+	return n, err
+}
+
+// Close closes the body and logs the finish of the request
+//
+// implements io.Closer
+func (body *countingBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.once.Do(func() { body.finish(body.received) })
+	return err
+}
+
+// redactURL gets a string version of the given URL without the values of its query parameters and its password
+func redactURL(location *url.URL) string {
+	redacted := *location
+	if len(redacted.RawQuery) > 0 {
+		query := redacted.Query()
+		for key, values := range query {
+			for i := range values {
+				values[i] = Redact(values[i])
+			}
+			query[key] = values
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
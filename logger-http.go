@@ -3,12 +3,18 @@ package logger
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"html"
+	"io"
 	"net"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/gildas/go-errors"
+	"github.com/gildas/go-core"
 	"github.com/google/uuid"
 )
 
@@ -17,6 +23,27 @@ type responseWriter struct {
 	statusCode int
 	written    uint64
 	Records    map[string]any
+	// bodyLimit is the number of bytes of the body to capture in body
+	bodyLimit int
+	body      []byte
+}
+
+// bodyCapture is a request body that captures the first bytes read from it
+type bodyCapture struct {
+	io.ReadCloser
+	limit    int
+	captured []byte
+}
+
+// Read reads from the request body and captures the first bytes
+//
+// implements io.Reader
+func (body *bodyCapture) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if remaining := body.limit - len(body.captured); remaining > 0 && n > 0 {
+		body.captured = append(body.captured, p[:min(n, remaining)]...)
+	}
+	return n, err
 }
 
 type responseRecorder interface {
@@ -46,41 +73,178 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
-// Hijack implements the http.Hijacker interface
+// Unwrap gets the wrapped ResponseWriter
 //
-// Hijack lets the caller take over the connection.
+// This is used by http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wrap gets the responseWriter with the optional interfaces of the ResponseWriter it wraps
 //
-// This is used by websockets (among others)
-func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
-		return hijacker.Hijack()
+// The handlers can check for http.Flusher, http.Hijacker, http.Pusher, and io.ReaderFrom,
+// so the responseWriter implements only the ones the wrapped ResponseWriter implements.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	_, pusher := w.ResponseWriter.(http.Pusher)
+	_, readerFrom := w.ResponseWriter.(io.ReaderFrom)
+
+	switch {
+	case flusher && hijacker && pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responseHijacker
+			responsePusher
+			responseReaderFrom
+		}{w, responseFlusher{w}, responseHijacker{w}, responsePusher{w}, responseReaderFrom{w}}
+	case flusher && hijacker && pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responseHijacker
+			responsePusher
+		}{w, responseFlusher{w}, responseHijacker{w}, responsePusher{w}}
+	case flusher && hijacker && !pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responseHijacker
+			responseReaderFrom
+		}{w, responseFlusher{w}, responseHijacker{w}, responseReaderFrom{w}}
+	case flusher && hijacker && !pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responseHijacker
+		}{w, responseFlusher{w}, responseHijacker{w}}
+	case flusher && !hijacker && pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responsePusher
+			responseReaderFrom
+		}{w, responseFlusher{w}, responsePusher{w}, responseReaderFrom{w}}
+	case flusher && !hijacker && pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responsePusher
+		}{w, responseFlusher{w}, responsePusher{w}}
+	case flusher && !hijacker && !pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+			responseReaderFrom
+		}{w, responseFlusher{w}, responseReaderFrom{w}}
+	case flusher && !hijacker && !pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseFlusher
+		}{w, responseFlusher{w}}
+	case !flusher && hijacker && pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseHijacker
+			responsePusher
+			responseReaderFrom
+		}{w, responseHijacker{w}, responsePusher{w}, responseReaderFrom{w}}
+	case !flusher && hijacker && pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseHijacker
+			responsePusher
+		}{w, responseHijacker{w}, responsePusher{w}}
+	case !flusher && hijacker && !pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseHijacker
+			responseReaderFrom
+		}{w, responseHijacker{w}, responseReaderFrom{w}}
+	case !flusher && hijacker && !pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responseHijacker
+		}{w, responseHijacker{w}}
+	case !flusher && !hijacker && pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responsePusher
+			responseReaderFrom
+		}{w, responsePusher{w}, responseReaderFrom{w}}
+	case !flusher && !hijacker && pusher && !readerFrom:
+		return struct {
+			*responseWriter
+			responsePusher
+		}{w, responsePusher{w}}
+	case !flusher && !hijacker && !pusher && readerFrom:
+		return struct {
+			*responseWriter
+			responseReaderFrom
+		}{w, responseReaderFrom{w}}
+	default:
+		return w
 	}
-	return nil, nil, errors.Join(errors.New("ResponseWrite does not implement http.Hijaker"), errors.InvalidType.With("responseWriter", "Hijacker"))
 }
 
-/*
-// Not sure yet if we need this
+// responseFlusher forwards http.Flusher to the ResponseWriter of a responseWriter
+type responseFlusher struct {
+	w *responseWriter
+}
 
-func (w *responseWriter) Flush() {
-	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
-	}
+// Flush sends any buffered data to the client
+//
+// implements http.Flusher
+func (flusher responseFlusher) Flush() {
+	flusher.w.ResponseWriter.(http.Flusher).Flush()
 }
 
-func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
-		return hijacker.Hijack()
-	}
-	return nil, nil, errors.NotImplemented.WithStack()
+// responseHijacker forwards http.Hijacker to the ResponseWriter of a responseWriter
+type responseHijacker struct {
+	w *responseWriter
+}
+
+// Hijack lets the caller take over the connection
+//
+// This is used by websockets (among others)
+//
+// implements http.Hijacker
+func (hijacker responseHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijacker.w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// responsePusher forwards http.Pusher to the ResponseWriter of a responseWriter
+type responsePusher struct {
+	w *responseWriter
+}
+
+// Push initiates an HTTP/2 server push
+//
+// implements http.Pusher
+func (pusher responsePusher) Push(target string, opts *http.PushOptions) error {
+	return pusher.w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// responseReaderFrom forwards io.ReaderFrom to the ResponseWriter of a responseWriter
+type responseReaderFrom struct {
+	w *responseWriter
 }
 
-func (w *responseWriter) RoundTrip(r *http.Request) (*http.Response, error) {
-	if roundtripper, ok := w.ResponseWriter.(http.RoundTripper); ok {
-		return roundtripper.RoundTrip(r)
+// ReadFrom reads data from src and writes it to the connection as part of an HTTP reply
+//
+// The ResponseWriter's io.ReaderFrom (like the one of net/http does to use sendfile) is used unless the body must be captured.
+//
+// implements io.ReaderFrom
+func (readerFrom responseReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	w := readerFrom.w
+	if len(w.body) < w.bodyLimit {
+		return io.Copy(w, src)
 	}
-	return nil, errors.NotImplemented.WithStack()
+	n, err := w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.written += uint64(n)
+	return n, err
 }
-*/
 
 // Write writes the data to the connection as part of an HTTP reply.
 //
@@ -106,9 +270,17 @@ func (w *responseWriter) RoundTrip(r *http.Request) (*http.Response, error) {
 func (w *responseWriter) Write(b []byte) (int, error) {
 	n, err := w.ResponseWriter.Write(b)
 	w.written += uint64(n)
+	w.capture(b[:n])
 	return n, err
 }
 
+// capture keeps the first bytes of the response body
+func (w *responseWriter) capture(b []byte) {
+	if remaining := w.bodyLimit - len(w.body); remaining > 0 && len(b) > 0 {
+		w.body = append(w.body, b[:min(len(b), remaining)]...)
+	}
+}
+
 // Record will add a key/value pair to the response writer records
 func (w *responseWriter) Record(key string, value any) {
 	if w.Records == nil {
@@ -124,7 +296,91 @@ func AddRecordToResponseWriter(rw http.ResponseWriter, key string, value any) {
 	}
 }
 
+// HttpHandlerOptions configures the HTTP handler returned by HttpHandlerWithOptions
+//
+// The zero value logs like HttpHandler does.
+type HttpHandlerOptions struct {
+	// RequestIDHeader is the header field to use for request ID (default: "X-Request-Id")
+	RequestIDHeader string
+
+	// SkipPaths are the URL paths of the requests that are not logged (e.g.: "/healthz")
+	SkipPaths []string
+
+	// SkipPatterns are the regular expressions matching the URL paths of the requests that are not logged
+	SkipPatterns []*regexp.Regexp
+
+	// FinishOnly tells to log only when the request finishes
+	FinishOnly bool
+
+	// SuccessSampling tells to log only 1 out of SuccessSampling requests (0 or 1: all requests are logged)
+	//
+	// Requests that are not sampled are still logged when they finish if they fail or if they are slow.
+	SuccessSampling uint64
+
+	// SlowThreshold is the duration after which a request is logged at WARN level (at least) when it finishes (0: never)
+	SlowThreshold time.Duration
+
+	// SuccessLevel is the level of the finish Record for status codes below 400 (default: INFO)
+	SuccessLevel Level
+
+	// ClientErrorLevel is the level of the finish Record for 4xx status codes (default: ERROR)
+	ClientErrorLevel Level
+
+	// ServerErrorLevel is the level of the finish Record for 5xx status codes (default: ERROR)
+	ServerErrorLevel Level
+
+	// StartMessage builds the message logged when the request starts
+	StartMessage func(r *http.Request) string
+
+	// FinishMessage builds the message logged when the request finishes
+	FinishMessage func(r *http.Request, statusCode int, duration time.Duration) string
+
+	// Fields builds extra records that are added to the Logger of the request
+	Fields func(r *http.Request) map[string]any
+
+	// RequestHeaders are the names of the request headers to log when the request finishes (key: "request_headers")
+	RequestHeaders []string
+
+	// ResponseHeaders are the names of the response headers to log when the request finishes (key: "response_headers")
+	ResponseHeaders []string
+
+	// RedactHeaders are the names of the headers whose values are redacted,
+	// on top of Authorization, Cookie, and Set-Cookie that are always redacted
+	RedactHeaders []string
+
+	// RequestBodySize is the number of bytes of the request body to log when the request finishes (key: "request_body")
+	//
+	// Only the bytes read by the handler are logged.
+	RequestBodySize int
+
+	// ResponseBodySize is the number of bytes of the response body to log when the request finishes (key: "response_body")
+	ResponseBodySize int
+
+	// DebugHeader is the request header that lowers the levels for a request (default: "X-Debug-Log")
+	//
+	// Its value is a level (e.g.: "trace"). The levels of the request Logger and of the request context (see Logger.Ctx)
+	// are lowered to that level, the streams and the other requests are not affected.
+	//
+	// The header is ignored unless DebugSecret or DebugAllowed is set.
+	DebugHeader string
+
+	// DebugSecret is the secret that signs the values of DebugHeader (see SignDebugLevel)
+	DebugSecret []byte
+
+	// DebugSecretFromEnvironment tells to use $LOG_DEBUG_SECRET if DebugSecret is empty
+	DebugSecretFromEnvironment bool
+
+	// DebugAllowed tells if a request can lower its levels with an unsigned value of DebugHeader (e.g.: an allow-list of addresses)
+	DebugAllowed func(r *http.Request) bool
+}
+
+// alwaysRedactedHeaders are the headers whose values are never logged
+var alwaysRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
 // HttpHandler function will wrap an http handler with extra logging information
+//
+// The W3C trace context received in the traceparent and tracestate headers is stored in the request context,
+// unless it already has one, and its identifiers are written in the records (see Logger.WithContext).
 func (l *Logger) HttpHandler() func(http.Handler) http.Handler {
 	return l.HttpHandlerWithRequestIDHeader("X-Request-Id")
 }
@@ -133,6 +389,45 @@ func (l *Logger) HttpHandler() func(http.Handler) http.Handler {
 //
 // It allows to specify the header field to use for request ID
 func (l *Logger) HttpHandlerWithRequestIDHeader(header string) func(http.Handler) http.Handler {
+	return l.httpHandler(header, HttpHandlerOptions{})
+}
+
+// HttpHandlerWithOptions function will wrap an http handler with extra logging information
+//
+// It allows to choose which requests are logged, how, and at which level.
+//
+// Requests that are skipped or sampled out still get a Logger with their request ID in their context.
+//
+// Example:
+//
+//	router.Use(log.HttpHandlerWithOptions(logger.HttpHandlerOptions{
+//		SkipPaths:     []string{"/healthz"},
+//		FinishOnly:    true,
+//		SlowThreshold: 2 * time.Second,
+//	}))
+func (l *Logger) HttpHandlerWithOptions(options HttpHandlerOptions) func(http.Handler) http.Handler {
+	if len(options.RequestIDHeader) == 0 {
+		options.RequestIDHeader = "X-Request-Id"
+	}
+	return l.httpHandler(options.RequestIDHeader, options)
+}
+
+// httpHandler wraps an http handler with the given request ID header and options
+func (l *Logger) httpHandler(header string, options HttpHandlerOptions) func(http.Handler) http.Handler {
+	if options.SuccessLevel == UNSET {
+		options.SuccessLevel = INFO
+	}
+	if options.ClientErrorLevel == UNSET {
+		options.ClientErrorLevel = ERROR
+	}
+	if options.ServerErrorLevel == UNSET {
+		options.ServerErrorLevel = ERROR
+	}
+	if len(options.DebugSecret) == 0 && options.DebugSecretFromEnvironment {
+		options.DebugSecret = []byte(core.GetEnvAsString(string(l.environmentPrefix)+"LOG_DEBUG_SECRET", ""))
+	}
+	var requests atomic.Uint64
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -152,34 +447,148 @@ func (l *Logger) HttpHandlerWithRequestIDHeader(header string) func(http.Handler
 				ctx = context.WithValue(ctx, "reqid", reqid)
 			}
 
+			// Get the W3C trace context of the request, if any
+			ctx = extractTraceContext(ctx, r.Header)
+
+			// Lower the levels of this request, if it is allowed to
+			if level, ok := options.debugLevel(r); ok {
+				ctx = contextWithDebugLevel(ctx, level)
+			}
+
 			// Get a new Child logger tailored to the request
-			reqLogger := l.Child("route", r.URL.Path, "reqid", reqid, "path", r.URL.Path, "remote", r.RemoteAddr)
-			reqLogger.Records(
-				"agent", r.UserAgent(),
-				"verb", r.Method,
-			).Infof("⏳ request start: %s %s", r.Method, html.EscapeString(r.URL.Path))
+			reqLogger := l.WithContext(ctx).Child("route", r.URL.Path, "reqid", reqid, "path", r.URL.Path, "remote", r.RemoteAddr)
+			if options.Fields != nil {
+				reqLogger = reqLogger.RecordMap(options.Fields(r))
+			}
+			if level, ok := debugLevelFromContext(ctx); ok {
+				reqLogger = reqLogger.withLoweredLevels(level)
+			}
+
+			logged := !options.skip(r.URL.Path)
+			sampled := logged && (options.SuccessSampling <= 1 || requests.Add(1)%options.SuccessSampling == 1)
+			if sampled && !options.FinishOnly {
+				template, message := "⏳ request start: %s %s", ""
+				if options.StartMessage != nil {
+					template = options.StartMessage(r)
+					message = template
+				} else {
+					message = fmt.Sprintf(template, r.Method, html.EscapeString(r.URL.Path))
+				}
+				reqLogger.Records(
+					"agent", r.UserAgent(),
+					"verb", r.Method,
+				).writeHttpMessage(INFO, template, message)
+			}
 
 			// Wrap the response writer to capture the status code
 			writer := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			request := r.WithContext(reqLogger.ToContext(ctx))
+
+			// Capture what was sent, if asked to
+			var requestBody *bodyCapture
+			if logged {
+				if len(options.RequestHeaders) > 0 {
+					writer.Record("request_headers", options.captureHeaders(r.Header, options.RequestHeaders))
+				}
+				if options.RequestBodySize > 0 && r.Body != nil && r.Body != http.NoBody {
+					requestBody = &bodyCapture{ReadCloser: r.Body, limit: options.RequestBodySize}
+					request.Body = requestBody
+				}
+				writer.bodyLimit = options.ResponseBodySize
+			}
 
 			// Serving the request
-			next.ServeHTTP(writer, r.WithContext(reqLogger.ToContext(ctx)))
+			next.ServeHTTP(writer.wrap(), request)
+
+			if !logged {
+				return
+			}
+			if requestBody != nil {
+				writer.Record("request_body", string(requestBody.captured))
+			}
+			if len(options.ResponseHeaders) > 0 {
+				writer.Record("response_headers", options.captureHeaders(w.Header(), options.ResponseHeaders))
+			}
+			if options.ResponseBodySize > 0 {
+				writer.Record("response_body", string(writer.body))
+			}
 
 			// Logging the duration of the request handling
 			duration := time.Since(start)
-			if writer.statusCode >= 400 {
-				reqLogger.RecordMap(writer.Records).Records(
-					"duration", duration.Seconds(),
-					"http_status", writer.statusCode,
-					"written", writer.written,
-				).Errorf("❌ request finish: %s %s in %s", r.Method, html.EscapeString(r.URL.Path), duration)
+			level := options.SuccessLevel
+			if writer.statusCode >= 500 {
+				level = options.ServerErrorLevel
+			} else if writer.statusCode >= 400 {
+				level = options.ClientErrorLevel
+			}
+			slow := options.SlowThreshold > 0 && duration > options.SlowThreshold
+			if slow && level < WARN {
+				level = WARN
+			}
+			if !sampled && !slow && writer.statusCode < 400 {
+				return
+			}
+			template, message := "✅ request finish: %s %s in %s", ""
+			if options.FinishMessage != nil {
+				template = options.FinishMessage(r, writer.statusCode, duration)
+				message = template
 			} else {
-				reqLogger.RecordMap(writer.Records).Records(
-					"duration", duration.Seconds(),
-					"http_status", writer.statusCode,
-					"written", writer.written,
-				).Infof("✅ request finish: %s %s in %s", r.Method, html.EscapeString(r.URL.Path), duration)
+				if writer.statusCode >= 400 {
+					template = "❌ request finish: %s %s in %s"
+				}
+				message = fmt.Sprintf(template, r.Method, html.EscapeString(r.URL.Path), duration)
 			}
+			reqLogger.RecordMap(writer.Records).Records(
+				"duration", duration.Seconds(),
+				"http_status", writer.statusCode,
+				"written", writer.written,
+			).writeHttpMessage(level, template, message)
 		})
 	}
 }
+
+// skip tells if the requests with the given path should not be logged
+func (options HttpHandlerOptions) skip(path string) bool {
+	if slices.Contains(options.SkipPaths, path) {
+		return true
+	}
+	for _, pattern := range options.SkipPatterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureHeaders gets the values of the given headers, redacted if needed
+//
+// Headers that are not present are ignored, multiple values are joined with ", ".
+func (options HttpHandlerOptions) captureHeaders(headers http.Header, names []string) map[string]string {
+	captured := make(map[string]string, len(names))
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		values := headers.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if options.redactHeader(name) {
+			captured[name] = Redact(strings.Join(values, ", "))
+		} else {
+			captured[name] = strings.Join(values, ", ")
+		}
+	}
+	return captured
+}
+
+// redactHeader tells if the value of the given header must be redacted
+func (options HttpHandlerOptions) redactHeader(name string) bool {
+	matches := func(redacted string) bool { return http.CanonicalHeaderKey(redacted) == name }
+	return slices.ContainsFunc(alwaysRedactedHeaders, matches) || slices.ContainsFunc(options.RedactHeaders, matches)
+}
+
+// writeHttpMessage writes a message of the HTTP handler at the given level
+func (log *Logger) writeHttpMessage(level Level, template, message string) {
+	if log.ShouldWrite(level, log.GetTopic(), log.GetScope()) {
+		log.sendMessage(2, level, template, message)
+	}
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// AsLogr gets a go-logr LogSink that writes to this Logger
+//
+// The records go through the Logger's Stream, so they are filtered by its LevelSet
+// with the Logger's topic and scope, and written to its destinations.
+//
+// V-levels are mapped to Level: V(0) is INFO, V(1) is DEBUG, V(2) and above are TRACE.
+// Errors are written at ERROR, like Errorf does, whatever the V-level.
+//
+// The first name given to WithName becomes the topic, the following ones become the scope (joined with "/").
+// The key/value pairs given to WithValues become Record fields (if the last value is missing, its key is ignored).
+//
+// Example:
+//
+//	ctrl.SetLogger(logr.New(log.AsLogr()))
+func (log *Logger) AsLogr() logr.LogSink {
+	return &logrSink{logger: log}
+}
+
+// logrSink is a go-logr LogSink that writes to a Logger
+type logrSink struct {
+	logger *Logger
+	names  []string
+	// depth is the number of stack frames between the caller and the LogSink
+	depth int
+}
+
+// Init receives the runtime information from logr
+//
+// implements logr.LogSink
+func (sink *logrSink) Init(info logr.RuntimeInfo) {
+	sink.depth = info.CallDepth
+}
+
+// Enabled tells if the Logger writes records at the given V-level
+//
+// implements logr.LogSink
+func (sink *logrSink) Enabled(level int) bool {
+	return sink.logger.ShouldWrite(levelFromLogr(level), sink.logger.GetTopic(), sink.logger.GetScope())
+}
+
+// Info writes a message at the given V-level
+//
+// implements logr.LogSink
+func (sink *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	log := sink.logger.RecordMap(logrValues(keysAndValues))
+	if log.ShouldWrite(levelFromLogr(level), log.GetTopic(), log.GetScope()) {
+		log.sendMessage(2+sink.depth, levelFromLogr(level), msg, msg)
+	}
+}
+
+// Error writes an error at the ERROR level
+//
+// implements logr.LogSink
+func (sink *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	log, template, args := sink.logger.RecordMap(logrValues(keysAndValues)).withError(strings.ReplaceAll(msg, "%", "%%"), []any{err})
+	if log.ShouldWrite(ERROR, log.GetTopic(), log.GetScope()) {
+		log.sendMessage(2+sink.depth, ERROR, template, fmt.Sprintf(template, args...))
+	}
+}
+
+// WithValues gets a LogSink with the given key/value pairs as Record fields
+//
+// implements logr.LogSink
+func (sink *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &logrSink{logger: sink.logger.RecordMap(logrValues(keysAndValues)), names: sink.names, depth: sink.depth}
+}
+
+// WithName gets a LogSink with the given name
+//
+// The first name becomes the topic, the following ones become the scope.
+//
+// implements logr.LogSink
+func (sink *logrSink) WithName(name string) logr.LogSink {
+	names := append(append([]string(nil), sink.names...), name)
+	log := sink.logger
+	if len(names) == 1 {
+		log = log.Topic(name)
+	} else {
+		log = log.Scope(strings.Join(names[1:], "/"))
+	}
+	return &logrSink{logger: log, names: names, depth: sink.depth}
+}
+
+// WithCallDepth gets a LogSink that skips more stack frames to find the caller
+//
+// implements logr.CallDepthLogSink
+func (sink *logrSink) WithCallDepth(depth int) logr.LogSink {
+	return &logrSink{logger: sink.logger, names: sink.names, depth: sink.depth + depth}
+}
+
+// logrValues converts logr key/value pairs into Record fields
+func logrValues(keysAndValues []any) map[string]any {
+	values := make(map[string]any, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			values[key] = keysAndValues[i+1]
+		} else {
+			values[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+		}
+	}
+	return values
+}
+
+// levelFromLogr converts a logr V-level into a Level
+func levelFromLogr(level int) Level {
+	switch {
+	case level <= 0:
+		return INFO
+	case level == 1:
+		return DEBUG
+	default:
+		return TRACE
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultLevelSignals gets the signals used by HandleLevelSignals when none is given
+func defaultLevelSignals() (filterMore, filterLess os.Signal) {
+	return syscall.SIGUSR1, syscall.SIGUSR2
+}
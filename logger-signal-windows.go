@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package logger
+
+import (
+	"os"
+)
+
+// defaultLevelSignals gets the signals used by HandleLevelSignals when none is given
+//
+// Windows has no user-defined signals, they must be given in LevelSignalOptions.
+func defaultLevelSignals() (filterMore, filterLess os.Signal) {
+	return nil, nil
+}
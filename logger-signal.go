@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// HandleReopenSignal reopens the Logger's streams whenever one of the given signals is received
+//
+// If no signal is given, SIGHUP is used.
+//
+// This allows external tools like logrotate (in "create" mode) to move the log files.
+//
+// The signals are not handled anymore once the context is cancelled.
+func (log *Logger) HandleReopenSignal(ctx context.Context, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, signals...)
+
+	go func() {
+		defer signal.Stop(signalChannel)
+		for {
+			select {
+			case sig := <-signalChannel:
+				if err := log.Reopen(); err != nil {
+					log.Child("logger", "reopen").Errorf("Failed to reopen the streams after receiving %s", sig, err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// LevelSignalOptions configures Logger.HandleLevelSignals
+type LevelSignalOptions struct {
+	// FilterMoreSignal is the signal that makes the streams filter more (default: SIGUSR1)
+	FilterMoreSignal os.Signal
+	// FilterLessSignal is the signal that makes the streams filter less (default: SIGUSR2)
+	FilterLessSignal os.Signal
+	// Topic is the topic to change the level of (default: any topic)
+	Topic string
+	// Scope is the scope to change the level of (default: any scope)
+	Scope string
+}
+
+// HandleLevelSignals changes the levels of the Logger's streams whenever one of the signals of the options is received
+//
+// By default, SIGUSR1 calls FilterMore and SIGUSR2 calls FilterLess.
+// On Windows, there is no default signal: the signals must be given in the options,
+// otherwise a warning is logged and no signal is handled.
+//
+// If a Topic or a Scope is given, only the level of that topic/scope is changed.
+//
+// The new LevelSet of each stream is logged at WARN.
+//
+// The signals are not handled anymore once the context is cancelled.
+//
+// Example:
+//
+//	log.HandleLevelSignals(ctx, logger.LevelSignalOptions{Topic: "db"})
+//	// kill -USR2 <pid> logs more of the "db" topic, kill -USR1 <pid> logs less of it
+func (log *Logger) HandleLevelSignals(ctx context.Context, options LevelSignalOptions) {
+	defaultMore, defaultLess := defaultLevelSignals()
+	if options.FilterMoreSignal == nil {
+		options.FilterMoreSignal = defaultMore
+	}
+	if options.FilterLessSignal == nil {
+		options.FilterLessSignal = defaultLess
+	}
+	signals := []os.Signal{}
+	for _, sig := range []os.Signal{options.FilterMoreSignal, options.FilterLessSignal} {
+		if sig != nil {
+			signals = append(signals, sig)
+		}
+	}
+	if len(signals) == 0 {
+		log.Child("logger", "levels").Warnf("No signal to change the levels with, they should be given in LevelSignalOptions")
+		return
+	}
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, signals...)
+
+	go func() {
+		defer signal.Stop(signalChannel)
+		for {
+			select {
+			case sig := <-signalChannel:
+				more := sig == options.FilterMoreSignal
+				if len(options.Topic) == 0 && len(options.Scope) == 0 {
+					if more {
+						log.FilterMore()
+					} else {
+						log.FilterLess()
+					}
+				} else {
+					for _, stream := range leafStreams(log) {
+						if setter, ok := stream.(FilterSetter); ok {
+							level := stream.GetFilterLevels().Get(options.Topic, options.Scope)
+							if more {
+								level = level.Next()
+							} else {
+								level = level.Previous()
+							}
+							setter.SetFilterLevel(level, options.Topic, options.Scope)
+						}
+					}
+				}
+				levels := []string{}
+				for _, stream := range leafStreams(log) {
+					levels = append(levels, stream.GetFilterLevels().String())
+				}
+				log.Child("logger", "levels").Warnf("Received %s, the levels are now %s", sig, strings.Join(levels, ", "))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
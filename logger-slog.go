@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"runtime"
+
+	"github.com/gildas/go-errors"
+)
+
+// AsSlogHandler gets a log/slog Handler that writes to this Logger
+//
+// The records go through the Logger's Stream, so they are filtered by its LevelSet
+// with the Logger's topic and scope, and written to its destinations.
+//
+// slog levels are mapped to Level: below slog.LevelDebug is TRACE, slog.LevelDebug is DEBUG,
+// slog.LevelInfo is INFO, slog.LevelWarn is WARN, slog.LevelError is ERROR, and slog.LevelError+4 or above is FATAL.
+//
+// Attributes given to WithAttrs become Record fields of a child Logger (so "topic" and "scope" attributes
+// change the topic and scope of the records), groups become nested objects.
+// The "topic" and "scope" attributes of a record are used to filter it as well, though, as slog asks Enabled first
+// with the topic and scope of the Handler, they cannot let through a record the Handler would not write.
+//
+// Attributes named "time", "level", or "msg" are renamed "fields.time", "fields.level", and "fields.msg",
+// so they do not overwrite the fields of the Record.
+//
+// Example:
+//
+//	slog.SetDefault(slog.New(log.AsSlogHandler()))
+func (log *Logger) AsSlogHandler() slog.Handler {
+	return &slogHandler{logger: log}
+}
+
+// slogReservedKeys contains the Record keys the slog attributes cannot overwrite
+var slogReservedKeys = map[string]bool{
+	"time":  true,
+	"level": true,
+	"msg":   true,
+}
+
+// slogHandler is a log/slog Handler that writes to a Logger
+type slogHandler struct {
+	logger *Logger
+	groups []string
+	// attrs contains the attributes added to each group in groups
+	attrs []map[string]any
+}
+
+// Enabled tells if the Logger writes records at the given level with its current topic and scope
+//
+// implements slog.Handler
+func (handler *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return handler.logger.ShouldWrite(levelFromSlog(level), handler.logger.GetTopic(), handler.logger.GetScope())
+}
+
+// Handle writes the given slog Record to the Logger
+//
+// implements slog.Handler
+func (handler *slogHandler) Handle(_ context.Context, slogRecord slog.Record) error {
+	log := handler.logger
+	level := levelFromSlog(slogRecord.Level)
+	values := make(map[string]any, slogRecord.NumAttrs())
+	slogRecord.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(values, attr)
+		return true
+	})
+	topic, scope := log.GetTopic(), log.GetScope()
+	if len(handler.groups) == 0 { // the attributes of a group cannot change the topic and scope
+		if value, ok := values["topic"].(string); ok {
+			topic = value
+		}
+		if value, ok := values["scope"].(string); ok {
+			scope = value
+		}
+	}
+	if !log.ShouldWrite(level, topic, scope) {
+		return nil
+	}
+
+	record, release := NewPooledRecord()
+	defer release()
+	if !slogRecord.Time.IsZero() { // as per slog.Handler, a zero time is ignored
+		record.Set("time", slogRecord.Time.UTC())
+	}
+	record.Set("level", level)
+	if slogRecord.PC != 0 && log.ShouldLogSourceInfo() {
+		frame, _ := runtime.CallersFrames([]uintptr{slogRecord.PC}).Next()
+		setSourceInfo(record, frame.Function, frame.File, frame.Line)
+	}
+	message := slogRecord.Message
+	for _, redactor := range log.redactors {
+		if msg, redacted := redactor.Redact(message); redacted {
+			message = msg
+			break
+		}
+	}
+	record.Set("msg", message)
+	record.template = slogRecord.Message
+	for key, value := range renameSlogReservedKeys(handler.nest(values)) {
+		record.Set(key, value)
+	}
+	return errors.WithStack(log.Write(record))
+}
+
+// WithAttrs gets a Handler with the given attributes
+//
+// implements slog.Handler
+func (handler *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return handler
+	}
+	values := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		addSlogAttr(values, attr)
+	}
+	if len(handler.groups) == 0 {
+		return &slogHandler{logger: handler.logger.RecordMap(renameSlogReservedKeys(values))}
+	}
+	last := len(handler.attrs) - 1
+	groupAttrs := append([]map[string]any(nil), handler.attrs...)
+	groupAttrs[last] = maps.Clone(groupAttrs[last])
+	maps.Copy(groupAttrs[last], values)
+	return &slogHandler{logger: handler.logger, groups: handler.groups, attrs: groupAttrs}
+}
+
+// WithGroup gets a Handler that writes the attributes in a group
+//
+// implements slog.Handler
+func (handler *slogHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return handler
+	}
+	return &slogHandler{
+		logger: handler.logger,
+		groups: append(append([]string(nil), handler.groups...), name),
+		attrs:  append(append([]map[string]any(nil), handler.attrs...), map[string]any{}),
+	}
+}
+
+// nest nests the given values in the groups of the handler
+//
+// Groups without any value are omitted.
+func (handler *slogHandler) nest(values map[string]any) map[string]any {
+	for i := len(handler.groups) - 1; i >= 0; i-- {
+		group := maps.Clone(handler.attrs[i])
+		maps.Copy(group, values)
+		if len(group) == 0 {
+			values = map[string]any{}
+			continue
+		}
+		values = map[string]any{handler.groups[i]: group}
+	}
+	return values
+}
+
+// renameSlogReservedKeys prefixes the keys that would overwrite the fields of a Record with "fields."
+func renameSlogReservedKeys(values map[string]any) map[string]any {
+	for key, value := range values {
+		if slogReservedKeys[key] {
+			delete(values, key)
+			values["fields."+key] = value
+		}
+	}
+	return values
+}
+
+// addSlogAttr adds the given slog attribute to the values
+func addSlogAttr(values map[string]any, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		values[attr.Key] = attr.Value.Any()
+		return
+	}
+	group := attr.Value.Group()
+	if len(group) == 0 {
+		return
+	}
+	if len(attr.Key) == 0 { // inline the group
+		for _, attr := range group {
+			addSlogAttr(values, attr)
+		}
+		return
+	}
+	nested := make(map[string]any, len(group))
+	for _, attr := range group {
+		addSlogAttr(nested, attr)
+	}
+	values[attr.Key] = nested
+}
+
+// levelFromSlog converts a log/slog level into a Level
+func levelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	case level < slog.LevelError+4:
+		return ERROR
+	default:
+		return FATAL
+	}
+}
+
+// levelToSlog converts a Level into a log/slog level
+func levelToSlog(level Level) slog.Level {
+	switch {
+	case level == ALWAYS:
+		return slog.LevelError + 8
+	case level >= FATAL:
+		return slog.LevelError + 4
+	case level >= ERROR:
+		return slog.LevelError
+	case level >= WARN:
+		return slog.LevelWarn
+	case level >= INFO:
+		return slog.LevelInfo
+	case level >= DEBUG:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithContext gets a Logger that writes the trace context found in the given context
+//
+// The trace context is the one of the active OpenTelemetry span,
+// or the one received by HttpHandler in the W3C traceparent and tracestate headers.
+//
+// The records "trace_id", "span_id", and "trace_flags" are added.
+// Converters map them to the native fields of their backend.
+//
+// If there is no trace context, the Logger itself is returned.
+func (log *Logger) WithContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return log
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return log
+	}
+	return log.Records(
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+		"trace_flags", spanContext.TraceFlags().String(),
+	)
+}
+
+// extractTraceContext stores the W3C trace context of the given headers in the context
+//
+// If the context already has a trace context (e.g.: from an OpenTelemetry middleware), it is kept.
+func extractTraceContext(ctx context.Context, headers http.Header) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(headers))
+}
@@ -287,6 +287,11 @@ func (log *Logger) GetRecord(key string) any {
 	if parent, ok := log.stream.(*Logger); ok {
 		return parent.GetRecord(key)
 	}
+	if stream, ok := log.stream.(*levelsStream); ok {
+		if parent, ok := stream.Streamer.(*Logger); ok {
+			return parent.GetRecord(key)
+		}
+	}
 	return nil
 }
 
@@ -316,19 +321,7 @@ func (log *Logger) Warnf(msg string, args ...any) { log.send(WARN, msg, args...)
 //
 // If the last argument is an error, a Record is added and the error string is added to the message
 func (log *Logger) Errorf(msg string, args ...any) {
-	logWithErr := log
-
-	if len(args) > 0 {
-		last := args[len(args)-1]
-
-		if last == nil {
-			logWithErr.send(ERROR, msg, args[:len(args)-1]...)
-			return
-		} else if err, ok := last.(error); ok {
-			logWithErr = log.Record("err", err)
-			msg = msg + ", Error: %+v"
-		}
-	}
+	logWithErr, msg, args := log.withError(msg, args)
 	logWithErr.send(ERROR, msg, args...)
 }
 
@@ -336,19 +329,7 @@ func (log *Logger) Errorf(msg string, args ...any) {
 //
 // If the last argument is an error, a Record is added and the error string is added to the message
 func (log *Logger) Fatalf(msg string, args ...any) {
-	logWithErr := log
-
-	if len(args) > 0 {
-		last := args[len(args)-1]
-
-		if last == nil {
-			logWithErr.send(FATAL, msg, args[:len(args)-1]...)
-			return
-		} else if err, ok := last.(error); ok {
-			logWithErr = log.Record("err", err)
-			msg = msg + ", Error: %+v"
-		}
-	}
+	logWithErr, msg, args := log.withError(msg, args)
 	logWithErr.send(FATAL, msg, args...)
 }
 
@@ -398,37 +379,87 @@ func (log *Logger) Memory() {
 // send writes a message to the Stream
 func (log *Logger) send(level Level, msg string, args ...any) {
 	if log.ShouldWrite(level, log.GetTopic(), log.GetScope()) {
-		record, release := NewPooledRecord()
+		record, release := log.newMessageRecord(3, level, msg, fmt.Sprintf(msg, args...))
 		defer release()
-		record.Set("time", time.Now().UTC())
-		record.Set("level", level)
-		if log.stream.ShouldLogSourceInfo() {
-			if counter, file, line, ok := runtime.Caller(2); ok {
-				funcname := runtime.FuncForPC(counter).Name()
-				i := strings.LastIndex(funcname, "/")
-				if i == -1 {
-					i = 0 // main func typically has no slash
-				}
-				i += strings.Index(funcname[i:], ".")
-
-				record.Set("file", filepath.Base(file))
-				record.Set("line", line)
-				record.Set("func", funcname[i+1:])
-				record.Set("package", funcname[:i])
-			}
+		if err := log.Write(record); err != nil {
+			reportError(errors.RuntimeError.Wrap(err))
 		}
-		message := fmt.Sprintf(msg, args...)
-		for _, redactor := range log.redactors {
-			if msg, redacted := redactor.Redact(message); redacted {
-				message = msg
-				break
-			}
+	}
+}
+
+// sendMessage writes a formatted message to the Stream
+//
+// template is the message before it was formatted with its arguments.
+//
+// skip is the number of stack frames to skip to find the caller of the Logger
+func (log *Logger) sendMessage(skip int, level Level, template, message string) {
+	record, release := log.newMessageRecord(skip+1, level, template, message)
+	defer release()
+	if err := log.Write(record); err != nil {
+		reportError(errors.RuntimeError.Wrap(err))
+	}
+}
+
+// withError tells if the last argument is an error
+//
+// If it is, a Record is added to the returned Logger and the error string is added to the message.
+//
+// If it is nil, it is removed from the arguments.
+func (log *Logger) withError(msg string, args []any) (*Logger, string, []any) {
+	if len(args) > 0 {
+		last := args[len(args)-1]
+
+		if last == nil {
+			return log, msg, args[:len(args)-1]
+		} else if err, ok := last.(error); ok {
+			return log.Record("err", err), msg + ", Error: %+v", args
 		}
-		record.Set("msg", message)
-		if err := log.Write(record); err != nil {
-			fmt.Fprintf(os.Stderr, "Logger error: %+v\n", errors.RuntimeError.Wrap(err))
+	}
+	return log, msg, args
+}
+
+// newMessageRecord creates a pooled Record for the given message
+//
+// skip is the number of stack frames to skip to find the caller of the Logger
+func (log *Logger) newMessageRecord(skip int, level Level, template, message string) (*Record, func()) {
+	record, release := NewPooledRecord()
+	record.Set("time", time.Now().UTC())
+	record.Set("level", level)
+	if log.stream.ShouldLogSourceInfo() {
+		if counter, file, line, ok := runtime.Caller(skip); ok {
+			setSourceInfo(record, runtime.FuncForPC(counter).Name(), file, line)
 		}
 	}
+	for _, redactor := range log.redactors {
+		if msg, redacted := redactor.Redact(message); redacted {
+			message = msg
+			break
+		}
+	}
+	record.Set("msg", message)
+	record.template = template
+	return record, release
+}
+
+// setSourceInfo sets the source information of the given Record
+func setSourceInfo(record *Record, funcname, file string, line int) {
+	i := strings.LastIndex(funcname, "/")
+	if i == -1 {
+		i = 0 // main func typically has no slash
+	}
+	i += strings.Index(funcname[i:], ".")
+
+	record.Set("file", filepath.Base(file))
+	record.Set("line", line)
+	record.Set("func", funcname[i+1:])
+	record.Set("package", funcname[:i])
+}
+
+// reportError reports errors that cannot be returned to the caller (the Logger cannot log its own errors)
+//
+// The caller should wrap the error with errors.RuntimeError so the stack trace starts where the error was reported
+func reportError(err error) {
+	fmt.Fprintf(os.Stderr, "Logger error: %+v\n", err)
 }
 
 func bytesToString(bytes uint64) string {
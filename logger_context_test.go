@@ -0,0 +1,189 @@
+package logger_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gildas/go-logger"
+)
+
+type ContextSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestContextSuite(t *testing.T) {
+	suite.Run(t, new(ContextSuite))
+}
+
+func (suite *ContextSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *ContextSuite) TestCanWriteWithContextMethods() {
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.TRACE)}
+	log := logger.Create("test", stream)
+	ctx := context.Background()
+	log.TracefCtx(ctx, "trace %d", 1)
+	log.DebugfCtx(ctx, "debug %d", 2)
+	log.InfofCtx(ctx, "info %d", 3)
+	log.WarnfCtx(ctx, "warn %d", 4)
+	log.ErrorfCtx(ctx, "error %d", 5)
+	log.FatalfCtx(ctx, "fatal %d", 6)
+	log.ErrorfCtx(ctx, "failed", errors.NotImplemented)
+
+	expected := []logger.Level{logger.TRACE, logger.DEBUG, logger.INFO, logger.WARN, logger.ERROR, logger.FATAL, logger.ERROR}
+	records := stream.Records()
+	suite.Require().Len(records, len(expected))
+	for i, level := range expected {
+		suite.Assert().Equal(level, records[i].Get("level"))
+	}
+	suite.Assert().Equal("info 3", records[2].Get("msg"))
+	suite.Assert().Equal(errors.NotImplemented, records[6].Get("err"), "ErrorfCtx should handle errors like Errorf")
+}
+
+func (suite *ContextSuite) TestCanWriteRecordsFromContext() {
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream).Record("user", "john")
+	ctx := logger.ContextWithRecords(context.Background(), "tenant", "acme", "user", "jane")
+	ctx = logger.ContextWithRecords(ctx, "order", 1234)
+	log.InfofCtx(ctx, "with records")
+	log.Infof("without records")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("acme", records[0].Get("tenant"))
+	suite.Assert().Equal(1234, records[0].Get("order"), "The records already in the context should be kept")
+	suite.Assert().Equal("jane", records[0].Get("user"), "The records of the context should win")
+	suite.Assert().Nil(records[1].Get("tenant"), "The Logger should not be modified")
+	suite.Assert().Equal("john", records[1].Get("user"))
+}
+
+func (suite *ContextSuite) TestCanWriteContextKeys() {
+	type tenantKey struct{}
+	logger.RegisterContextKey("tenant", tenantKey{})
+	defer logger.RegisterContextKey("tenant", nil)
+
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	//nolint:staticcheck
+	ctx := context.WithValue(context.Background(), "reqid", "1234")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	log.InfofCtx(ctx, "with keys")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("1234", records[0].Get("reqid"), "The request id stored by HttpHandler should be written")
+	suite.Assert().Equal("acme", records[0].Get("tenant"))
+}
+
+func (suite *ContextSuite) TestCanWriteTraceContext() {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	stream := &RecordingStream{}
+	logger.Create("test", stream).InfofCtx(ctx, "with trace")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", records[0].Get("trace_id"))
+}
+
+func (suite *ContextSuite) TestCanOverrideLevelsWithContext() {
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	verbose := logger.ContextWithLevels(context.Background(), logger.NewLevelSet(logger.TRACE))
+	quiet := logger.ContextWithLevels(context.Background(), logger.NewLevelSet(logger.ERROR))
+
+	log.DebugfCtx(verbose, "verbose debug")
+	log.Ctx(verbose).Child("db", "query").Tracef("verbose child trace")
+	log.DebugfCtx(context.Background(), "normal debug")
+	log.InfofCtx(quiet, "quiet info")
+	log.ErrorfCtx(quiet, "quiet error")
+	log.Debugf("plain debug")
+
+	suite.Assert().Equal([]string{"verbose debug", "verbose child trace", "quiet error"}, stream.Messages())
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), log.GetFilterLevels(), "The streams should not be modified")
+	suite.Assert().Equal(logger.NewLevelSet(logger.TRACE), log.Ctx(verbose).GetFilterLevels())
+
+	levels, found := logger.LevelsFromContext(verbose)
+	suite.Assert().True(found)
+	suite.Assert().Equal(logger.NewLevelSet(logger.TRACE), levels)
+	_, found = logger.LevelsFromContext(context.Background())
+	suite.Assert().False(found)
+}
+
+func (suite *ContextSuite) TestCanChangeStreamsThroughContextLogger() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log"), FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	defer log.Close()
+	ctxLogger := log.Ctx(logger.ContextWithLevels(context.Background(), logger.NewLevelSet(logger.TRACE)))
+
+	ctxLogger.SetFilterLevel(logger.DEBUG, "db")
+	suite.Assert().Equal(logger.DEBUG, stream.FilterLevels.Get("db", ""))
+	ctxLogger.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault())
+	ctxLogger.FilterLess()
+	suite.Assert().Equal(logger.INFO, stream.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.NewLevelSet(logger.TRACE), ctxLogger.GetFilterLevels(), "The levels of the context should be kept")
+
+	ctxLogger.Infof("before")
+	suite.Require().NoError(os.Rename(stream.Path, stream.Path+".1"))
+	suite.Require().NoError(ctxLogger.Reopen())
+	ctxLogger.Infof("after")
+	stream.Flush()
+	content, err := os.ReadFile(stream.Path)
+	suite.Require().NoError(err, "The stream should have been reopened")
+	suite.Assert().Contains(string(content), `"msg":"after"`)
+}
+
+func (suite *ContextSuite) TestShouldReturnSameLoggerWithEmptyContext() {
+	log := logger.Create("test", &RecordingStream{})
+	suite.Assert().Same(log, log.Ctx(context.Background()))
+	suite.Assert().Same(log, log.Ctx(nil)) //nolint:staticcheck
+}
+
+func (suite *ContextSuite) TestCanUseContextFromHttpHandler() {
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	handler := log.HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqLogger := logger.Must(logger.FromContext(r.Context()))
+		suite.Assert().Equal("/", reqLogger.GetScope(), "FromContext should still return the request Logger")
+		log.InfofCtx(r.Context(), "from the application Logger")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "1234")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	suite.Assert().Contains(stream.Messages(), "from the application Logger")
+	for _, record := range stream.Records() {
+		suite.Assert().Equal("1234", record.Get("reqid"), "Record %s should have the request id", record.Get("msg"))
+	}
+}
+
+func (suite *ContextSuite) TestCanWriteSourceInfoWithContextMethods() {
+	stream := &RecordingStream{SourceInfo: true}
+	log := logger.Create("test", stream)
+	ctx := logger.ContextWithLevels(context.Background(), logger.NewLevelSet(logger.TRACE))
+	log.InfofCtx(ctx, "info")
+	log.ErrorfCtx(ctx, "error", errors.NotImplemented)
+	log.Ctx(ctx).Tracef("trace")
+
+	records := stream.Records()
+	suite.Require().Len(records, 3)
+	for _, record := range records {
+		suite.Assert().Equal("logger_context_test.go", record.Get("file"), "Record %s has the wrong file", record.Get("msg"))
+	}
+}
@@ -0,0 +1,174 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type LevelHandlerSuite struct {
+	suite.Suite
+	Name string
+}
+
+type levelsResponse struct {
+	Streams []struct {
+		Stream string `json:"stream"`
+		Levels string `json:"levels"`
+	} `json:"streams"`
+	Expires *time.Time `json:"expires"`
+}
+
+func TestLevelHandlerSuite(t *testing.T) {
+	suite.Run(t, new(LevelHandlerSuite))
+}
+
+func (suite *LevelHandlerSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// newStreams creates two streams that filter at INFO
+func (suite *LevelHandlerSuite) newStreams() (*logger.WriterStream, *logger.WriterStream) {
+	return &logger.WriterStream{Writer: io.Discard, Unbuffered: true, FilterLevels: logger.NewLevelSet(logger.INFO)},
+		&logger.WriterStream{Writer: io.Discard, Unbuffered: true, FilterLevels: logger.ParseLevels("WARN;DEBUG:{db}")}
+}
+
+// request sends a request to the handler and decodes its response
+func (suite *LevelHandlerSuite) request(handler http.Handler, method, target, body string) (int, levelsResponse) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	var response levelsResponse
+	if recorder.Code == http.StatusOK {
+		suite.Assert().Equal("application/json", recorder.Header().Get("Content-Type"))
+		suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	}
+	return recorder.Code, response
+}
+
+func (suite *LevelHandlerSuite) TestCanShowLevels() {
+	stream1, stream2 := suite.newStreams()
+	log := logger.Create("test", logger.CreateMultiStream(stream1, &logger.AsyncStream{Destination: stream2}))
+	defer log.Close()
+
+	status, response := suite.request(log.Child("topic", "scope").LevelHandler(), http.MethodGet, "/", "")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Len(response.Streams, 2, "The streams should be listed through the MultiStream and the AsyncStream")
+	suite.Assert().Equal("INFO", response.Streams[0].Levels)
+	suite.Assert().Equal(stream1.String(), response.Streams[0].Stream)
+	suite.Assert().Equal(stream2.FilterLevels, logger.ParseLevels(response.Streams[1].Levels))
+	suite.Assert().Nil(response.Expires)
+}
+
+func (suite *LevelHandlerSuite) TestCanSetLevels() {
+	stream1, stream2 := suite.newStreams()
+	log := logger.Create("test", stream1, stream2)
+	handler := log.LevelHandler()
+
+	status, response := suite.request(handler, http.MethodPut, "/", "DEBUG;TRACE:{db:query}")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Assert().Nil(response.Expires)
+	suite.Assert().Equal(logger.DEBUG, stream1.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.TRACE, stream1.FilterLevels.Get("db", "query"))
+	suite.Assert().Equal(logger.DEBUG, stream2.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.DEBUG, stream2.FilterLevels.Get("db", "other"), "The levels that were not given should be kept")
+	suite.Assert().Equal(logger.TRACE, stream2.FilterLevels.Get("db", "query"))
+	suite.Require().Len(response.Streams, 2)
+	suite.Assert().Equal(stream1.FilterLevels, logger.ParseLevels(response.Streams[0].Levels))
+
+	status, _ = suite.request(handler, http.MethodPost, "/", "INFO")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Assert().Equal(logger.INFO, stream1.FilterLevels.GetDefault())
+}
+
+func (suite *LevelHandlerSuite) TestCanRevertLevelsAfterTTL() {
+	stream1, stream2 := suite.newStreams()
+	log := logger.Create("test", stream1, stream2)
+	handler := log.LevelHandler()
+
+	status, response := suite.request(handler, http.MethodPut, "/?ttl=100ms", "TRACE:{db}")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().NotNil(response.Expires)
+	suite.Assert().WithinDuration(time.Now().Add(100*time.Millisecond), *response.Expires, time.Second)
+	suite.Assert().Equal(logger.TRACE, stream1.FilterLevels.Get("db", "query"))
+
+	// A second change before the TTL expires is reverted with the first one
+	status, _ = suite.request(handler, http.MethodPut, "/?ttl=100ms", "DEBUG")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Assert().Equal(logger.DEBUG, stream2.FilterLevels.GetDefault())
+
+	suite.Assert().Eventually(func() bool {
+		_, response := suite.request(handler, http.MethodGet, "/", "")
+		return response.Expires == nil
+	}, time.Second, 10*time.Millisecond)
+	suite.Assert().Equal(logger.INFO, stream1.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.INFO, stream1.FilterLevels.Get("db", "query"))
+	suite.Assert().Equal(logger.WARN, stream2.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.DEBUG, stream2.FilterLevels.Get("db", "query"))
+}
+
+func (suite *LevelHandlerSuite) TestShouldRestoreLevelSetAfterTTL() {
+	stream1, stream2 := suite.newStreams()
+	before1, before2 := stream1.FilterLevels.Clone(), stream2.FilterLevels.Clone()
+	log := logger.Create("test", stream1, stream2)
+	handler := log.LevelHandler()
+
+	status, _ := suite.request(handler, http.MethodPut, "/?ttl=50ms", "DEBUG;TRACE:{db:query};ERROR:{http}")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().NotEqual(before2, stream2.FilterLevels)
+
+	suite.Assert().Eventually(func() bool {
+		_, response := suite.request(handler, http.MethodGet, "/", "")
+		return response.Expires == nil
+	}, time.Second, 10*time.Millisecond)
+	suite.Assert().Equal(before1, stream1.FilterLevels, "The topics/scopes that were not set should be removed")
+	suite.Assert().Equal(before2, stream2.FilterLevels, "The topics/scopes that were not set should be removed")
+}
+
+func (suite *LevelHandlerSuite) TestShouldKeepLevelsChangedWithoutTTL() {
+	stream1, _ := suite.newStreams()
+	log := logger.Create("test", stream1)
+	handler := log.LevelHandler()
+
+	status, _ := suite.request(handler, http.MethodPut, "/?ttl=50ms", "TRACE")
+	suite.Require().Equal(http.StatusOK, status)
+	status, response := suite.request(handler, http.MethodPut, "/", "DEBUG")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Assert().Nil(response.Expires)
+
+	time.Sleep(150 * time.Millisecond)
+	suite.Assert().Equal(logger.DEBUG, stream1.FilterLevels.GetDefault(), "The levels should not be reverted")
+}
+
+func (suite *LevelHandlerSuite) TestFailsSettingInvalidLevels() {
+	stream1, _ := suite.newStreams()
+	log := logger.Create("test", stream1)
+	handler := log.LevelHandler()
+
+	for _, test := range []struct{ target, body string }{
+		{"/", ""},
+		{"/", "LOUD"},
+		{"/", "DEBUG;LOUD:{db}"},
+		{"/?ttl=soon", "DEBUG"},
+		{"/?ttl=-1m", "DEBUG"},
+	} {
+		status, _ := suite.request(handler, http.MethodPut, test.target, test.body)
+		suite.Assert().Equal(http.StatusBadRequest, status, "%s %s", test.target, test.body)
+	}
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), stream1.FilterLevels, "The levels should not change")
+}
+
+func (suite *LevelHandlerSuite) TestFailsWithUnsupportedMethod() {
+	recorder := httptest.NewRecorder()
+	logger.Create("test", &logger.NilStream{}).LevelHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/", nil))
+	suite.Assert().Equal(http.StatusMethodNotAllowed, recorder.Code)
+	suite.Assert().Contains(recorder.Header().Get("Allow"), "PUT")
+}
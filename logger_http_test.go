@@ -0,0 +1,465 @@
+package logger_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type HttpHandlerSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestHttpHandlerSuite(t *testing.T) {
+	suite.Run(t, new(HttpHandlerSuite))
+}
+
+func (suite *HttpHandlerSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// serve serves a request with the given path through the given middleware
+//
+// The handler replies with the status given in the "status" query parameter and sleeps for the "sleep" query parameter.
+func (suite *HttpHandlerSuite) serve(middleware func(http.Handler) http.Handler, target string) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log, err := logger.FromContext(r.Context())
+		suite.Require().NoError(err, "The request should always have a Logger")
+		suite.Assert().NotEmpty(log.GetRecord("reqid"), "The request should always have a request ID")
+		if sleep, err := time.ParseDuration(r.URL.Query().Get("sleep")); err == nil {
+			time.Sleep(sleep)
+		}
+		if status := r.URL.Query().Get("status"); len(status) > 0 {
+			var code int
+			_, _ = fmt.Sscanf(status, "%d", &code)
+			w.WriteHeader(code)
+		}
+	})
+	middleware(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+}
+
+func (suite *HttpHandlerSuite) TestCanLogWithDefaultOptions() {
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	middleware := log.HttpHandlerWithOptions(logger.HttpHandlerOptions{})
+	suite.serve(middleware, "/")
+	suite.serve(middleware, "/?status=404")
+
+	records := stream.Records()
+	suite.Require().Len(records, 4)
+	suite.Assert().Equal("⏳ request start: GET /", records[0].Get("msg"))
+	suite.Assert().Equal(logger.INFO, records[1].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[1].Get("msg").(string), "✅ request finish: GET / in "))
+	suite.Assert().Equal(logger.ERROR, records[3].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[3].Get("msg").(string), "❌ request finish: GET / in "))
+}
+
+func (suite *HttpHandlerSuite) TestCanSkipPaths() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		SkipPaths:    []string{"/healthz"},
+		SkipPatterns: []*regexp.Regexp{regexp.MustCompile(`^/static/`)},
+	})
+	suite.serve(middleware, "/healthz")
+	suite.serve(middleware, "/static/logo.png")
+	suite.serve(middleware, "/static/missing.png?status=404")
+	suite.serve(middleware, "/api/users")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	for _, record := range records {
+		suite.Assert().Equal("/api/users", record.Get("path"))
+	}
+}
+
+func (suite *HttpHandlerSuite) TestCanLogFinishOnly() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{FinishOnly: true})
+	suite.serve(middleware, "/")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal(http.StatusOK, records[0].Get("http_status"))
+}
+
+func (suite *HttpHandlerSuite) TestCanSampleSuccessfulRequests() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{SuccessSampling: 5})
+	for range 10 {
+		suite.serve(middleware, "/")
+	}
+	suite.Assert().Len(stream.Records(), 4, "2 requests out of 10 should be logged")
+
+	for range 4 {
+		suite.serve(middleware, "/?status=500")
+	}
+	finished := 0
+	for _, record := range stream.Records()[4:] {
+		if record.Get("http_status") == http.StatusInternalServerError {
+			suite.Assert().Equal(logger.ERROR, record.Get("level"))
+			finished++
+		}
+	}
+	suite.Assert().Equal(4, finished, "Failed requests should always be logged when they finish")
+}
+
+func (suite *HttpHandlerSuite) TestCanEscalateSlowRequests() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:      true,
+		SuccessSampling: 100,
+		SlowThreshold:   10 * time.Millisecond,
+	})
+	suite.serve(middleware, "/") // sampled
+	suite.serve(middleware, "/") // not sampled and fast
+	suite.serve(middleware, "/?sleep=20ms")
+	suite.serve(middleware, "/?sleep=20ms&status=500")
+
+	records := stream.Records()
+	suite.Require().Len(records, 3)
+	suite.Assert().Equal(logger.INFO, records[0].Get("level"))
+	suite.Assert().Equal(logger.WARN, records[1].Get("level"), "Slow requests should be logged at WARN even if not sampled")
+	suite.Assert().Equal(logger.ERROR, records[2].Get("level"), "Slow failed requests should keep their level")
+}
+
+func (suite *HttpHandlerSuite) TestCanSetLevelPerStatusClass() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:       true,
+		SuccessLevel:     logger.DEBUG,
+		ClientErrorLevel: logger.WARN,
+		ServerErrorLevel: logger.FATAL,
+	})
+	suite.serve(middleware, "/?status=302")
+	suite.serve(middleware, "/?status=404")
+	suite.serve(middleware, "/?status=503")
+
+	records := stream.Records()
+	suite.Require().Len(records, 3)
+	suite.Assert().Equal(logger.DEBUG, records[0].Get("level"))
+	suite.Assert().Equal(logger.WARN, records[1].Get("level"))
+	suite.Assert().Equal(logger.FATAL, records[2].Get("level"))
+}
+
+func (suite *HttpHandlerSuite) TestCanCustomizeMessagesAndFields() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		RequestIDHeader: "X-Correlation-Id",
+		StartMessage: func(r *http.Request) string {
+			return "IN " + r.URL.Path
+		},
+		FinishMessage: func(r *http.Request, statusCode int, duration time.Duration) string {
+			return fmt.Sprintf("OUT %s %d (100%%)", r.URL.Path, statusCode)
+		},
+		Fields: func(r *http.Request) map[string]any {
+			return map[string]any{"tenant": r.URL.Query().Get("tenant")}
+		},
+	})
+	suite.serve(middleware, "/users?tenant=acme")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("IN /users", records[0].Get("msg"))
+	suite.Assert().Equal("OUT /users 200 (100%)", records[1].Get("msg"))
+	for _, record := range records {
+		suite.Assert().Equal("acme", record.Get("tenant"))
+	}
+}
+
+func (suite *HttpHandlerSuite) TestCanCaptureHeadersAndBodies() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:       true,
+		RequestHeaders:   []string{"content-type", "Authorization", "Cookie", "X-Api-Key", "X-Missing"},
+		ResponseHeaders:  []string{"Content-Type", "Set-Cookie"},
+		RedactHeaders:    []string{"x-api-key"},
+		RequestBodySize:  5,
+		ResponseBodySize: 8,
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		suite.Require().NoError(err)
+		suite.Assert().Equal(`{"name":"john"}`, string(body), "The handler should read the whole body")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Set-Cookie", "session=1234")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":`))
+		_, _ = w.Write([]byte(`"1234"}`))
+	})
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"john"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Add("Cookie", "a=1")
+	req.Header.Add("Cookie", "b=2")
+	req.Header.Set("X-Api-Key", "secret")
+	res := httptest.NewRecorder()
+	middleware(handler).ServeHTTP(res, req)
+	suite.Assert().Equal(`{"id":"1234"}`, res.Body.String(), "The response should not be modified")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal(map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "REDACTED",
+		"Cookie":        "REDACTED",
+		"X-Api-Key":     "REDACTED",
+	}, records[0].Get("request_headers"))
+	suite.Assert().Equal(map[string]string{
+		"Content-Type": "application/json",
+		"Set-Cookie":   "REDACTED",
+	}, records[0].Get("response_headers"))
+	suite.Assert().Equal(`{"nam`, records[0].Get("request_body"))
+	suite.Assert().Equal(`{"id":"1`, records[0].Get("response_body"))
+	suite.Assert().Equal(uint64(13), records[0].Get("written"))
+}
+
+func (suite *HttpHandlerSuite) TestShouldNotCaptureByDefault() {
+	stream := &RecordingStream{}
+	suite.serve(logger.Create("test", stream).HttpHandler(), "/")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	for _, key := range []string{"request_headers", "response_headers", "request_body", "response_body"} {
+		suite.Assert().Nil(records[1].Get(key), "Record %s should not be captured", key)
+	}
+}
+
+func (suite *HttpHandlerSuite) TestCanFlushStreamingResponses() {
+	flushed := make(chan struct{})
+	stream := &RecordingStream{}
+	server := httptest.NewServer(logger.Create("test", stream).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		suite.Require().True(ok, "The ResponseWriter should be a http.Flusher")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: hello\n\n"))
+		flusher.Flush()
+		<-flushed // the client must receive the event before the handler returns
+		suite.Require().NoError(http.NewResponseController(w).Flush(), "http.ResponseController should find the Flusher")
+	})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	suite.Require().NoError(err)
+	defer func() { _ = res.Body.Close() }()
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Assert().Equal("data: hello\n", line)
+	close(flushed)
+	_, _ = io.Copy(io.Discard, res.Body)
+}
+
+func (suite *HttpHandlerSuite) TestCanCountBytesWrittenWithReaderFrom() {
+	stream := &RecordingStream{}
+	server := httptest.NewServer(logger.Create("test", stream).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readerFrom, ok := w.(io.ReaderFrom)
+		suite.Require().True(ok, "The ResponseWriter should be an io.ReaderFrom")
+		_, err := readerFrom.ReadFrom(strings.NewReader("hello world"))
+		suite.Require().NoError(err)
+	})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	suite.Require().NoError(err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	suite.Assert().Equal("hello world", string(body))
+
+	suite.Require().Eventually(func() bool { return len(stream.Records()) == 2 }, time.Second, 10*time.Millisecond)
+	suite.Assert().Equal(uint64(11), stream.Records()[1].Get("written"))
+}
+
+func (suite *HttpHandlerSuite) TestCanCaptureBodyWithReaderFrom() {
+	stream := &RecordingStream{}
+	middleware := logger.Create("test", stream).HttpHandlerWithOptions(logger.HttpHandlerOptions{FinishOnly: true, ResponseBodySize: 5})
+	res := httptest.NewRecorder()
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, strings.NewReader("hello world"))
+	})).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	suite.Assert().Equal("hello world", res.Body.String())
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("hello", records[0].Get("response_body"))
+	suite.Assert().Equal(uint64(11), records[0].Get("written"))
+}
+
+func (suite *HttpHandlerSuite) TestCanUnwrapResponseWriter() {
+	res := httptest.NewRecorder()
+	logger.Create("test", &RecordingStream{}).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		suite.Require().True(ok, "The ResponseWriter should have an Unwrap method")
+		suite.Assert().Same(res, unwrapper.Unwrap())
+	})).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// writerOnly is a ResponseWriter without any optional interface
+type writerOnly struct {
+	http.ResponseWriter
+}
+
+func (suite *HttpHandlerSuite) TestShouldNotAddInterfacesToResponseWriter() {
+	res := httptest.NewRecorder()
+	stream := &RecordingStream{}
+	logger.Create("test", stream).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		suite.Assert().False(ok, "The ResponseWriter should not be a http.Flusher")
+		_, ok = w.(http.Hijacker)
+		suite.Assert().False(ok, "The ResponseWriter should not be a http.Hijacker")
+		_, ok = w.(http.Pusher)
+		suite.Assert().False(ok, "The ResponseWriter should not be a http.Pusher")
+		_, ok = w.(io.ReaderFrom)
+		suite.Assert().False(ok, "The ResponseWriter should not be an io.ReaderFrom")
+		suite.Assert().ErrorIs(http.NewResponseController(w).Flush(), http.ErrNotSupported)
+
+		_, _ = io.Copy(w, strings.NewReader("hello"))
+	})).ServeHTTP(writerOnly{res}, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	suite.Assert().Equal("hello", res.Body.String())
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(uint64(5), records[1].Get("written"))
+}
+
+// serveDebug serves a request that traces through the request Logger and through the request context
+func (suite *HttpHandlerSuite) serveDebug(log *logger.Logger, middleware func(http.Handler) http.Handler, debugHeader string) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Must(logger.FromContext(r.Context())).Child("db", "query").Tracef("request trace")
+		log.DebugfCtx(r.Context(), "context debug")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if len(debugHeader) > 0 {
+		req.Header.Set("X-Debug-Log", debugHeader)
+	}
+	middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func (suite *HttpHandlerSuite) TestCanLowerLevelsWithSignedDebugHeader() {
+	secret := []byte("s3cr3t")
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	middleware := log.HttpHandlerWithOptions(logger.HttpHandlerOptions{FinishOnly: true, DebugSecret: secret})
+
+	suite.serveDebug(log, middleware, logger.SignDebugLevel(secret, logger.TRACE, time.Now().Add(time.Hour)))
+	suite.Assert().Contains(stream.Messages(), "request trace")
+	suite.Assert().Contains(stream.Messages(), "context debug")
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), stream.GetFilterLevels(), "The streams should not be modified")
+
+	for name, value := range map[string]string{
+		"no header":         "",
+		"unsigned":          "trace",
+		"expired":           logger.SignDebugLevel(secret, logger.TRACE, time.Now().Add(-time.Minute)),
+		"wrong secret":      logger.SignDebugLevel([]byte("wrong"), logger.TRACE, time.Now().Add(time.Hour)),
+		"tampered":          strings.Replace(logger.SignDebugLevel(secret, logger.DEBUG, time.Now().Add(time.Hour)), "debug", "trace", 1),
+		"tampered lifetime": strings.Replace(logger.SignDebugLevel(secret, logger.TRACE, time.Now().Add(time.Hour)), ";exp=", ";exp=9", 1),
+	} {
+		stream.Reset()
+		suite.serveDebug(log, middleware, value)
+		suite.Assert().Len(stream.Records(), 1, "Only the finish line should be written with %s header", name)
+	}
+}
+
+func (suite *HttpHandlerSuite) TestCanLowerLevelsWithAllowedDebugHeader() {
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	allowed := true
+	middleware := log.HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:   true,
+		DebugAllowed: func(r *http.Request) bool { return allowed },
+	})
+
+	suite.serveDebug(log, middleware, "debug")
+	suite.Assert().Equal([]string{"context debug"}, stream.Messages()[:1], "DEBUG should be written, not TRACE")
+
+	stream.Reset()
+	allowed = false
+	suite.serveDebug(log, middleware, "trace")
+	suite.Assert().Len(stream.Records(), 1, "Requests that are not allowed should not lower their levels")
+
+	stream.Reset()
+	allowed = true
+	suite.serveDebug(log, middleware, "bogus")
+	suite.Assert().Len(stream.Records(), 1, "Invalid levels should be ignored")
+}
+
+func (suite *HttpHandlerSuite) TestShouldIgnoreDebugHeaderByDefault() {
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	suite.serveDebug(log, log.HttpHandler(), "trace")
+	suite.Assert().Len(stream.Records(), 2, "Only the start and finish lines should be written")
+}
+
+func (suite *HttpHandlerSuite) TestShouldKeepLowerLevelsWithDebugHeader() {
+	levels := logger.NewLevelSet(logger.WARN)
+	levels.Set(logger.TRACE, "db", "")
+	levels.Set(logger.NEVER, "noisy", "")
+	stream := &RecordingStream{FilterLevels: levels}
+	log := logger.Create("test", stream)
+	middleware := log.HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:   true,
+		DebugAllowed: func(r *http.Request) bool { return true },
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqLogger := logger.Must(logger.FromContext(r.Context()))
+		reqLogger.Infof("request info")
+		reqLogger.Debugf("request debug")
+		reqLogger.Child("db", nil).Tracef("db trace")
+		reqLogger.Child("noisy", nil).Errorf("noisy error")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Debug-Log", "info")
+	middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	messages := stream.Messages()
+	suite.Require().Len(messages, 3)
+	suite.Assert().Equal([]string{"request info", "db trace"}, messages[:2], "Levels lower than the debug level should be kept, NEVER too")
+}
+
+func (suite *HttpHandlerSuite) TestCanLowerLevelsWithDebugSecretFromEnvironment() {
+	suite.T().Setenv("LOG_DEBUG_SECRET", "s3cr3t")
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", stream)
+	value := logger.SignDebugLevel([]byte("s3cr3t"), logger.TRACE, time.Now().Add(time.Minute))
+
+	suite.serveDebug(log, log.HttpHandler(), value)
+	suite.Assert().Len(stream.Records(), 2, "HttpHandler should not use the secret of the environment")
+
+	stream.Reset()
+	suite.serveDebug(log, log.HttpHandlerWithOptions(logger.HttpHandlerOptions{DebugSecretFromEnvironment: true}), value)
+	suite.Assert().Contains(stream.Messages(), "request trace")
+}
+
+func (suite *HttpHandlerSuite) TestShouldLowerLevelsOfEachStreamWithDebugHeader() {
+	stream1 := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.WARN)}
+	stream2 := &RecordingStream{FilterLevels: logger.ParseLevels("ERROR;TRACE:{db}")}
+	log := logger.Create("test", stream1, stream2)
+	middleware := log.HttpHandlerWithOptions(logger.HttpHandlerOptions{
+		FinishOnly:   true,
+		DebugAllowed: func(r *http.Request) bool { return true },
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqLogger := logger.Must(logger.FromContext(r.Context()))
+		reqLogger.Infof("request info")
+		reqLogger.Debugf("request debug")
+		reqLogger.Child("db", nil).Tracef("db trace")
+		log.Child("db", nil).TracefCtx(r.Context(), "context db trace")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Debug-Log", "info")
+	middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	messages := stream2.Messages()
+	suite.Require().Len(messages, 4)
+	suite.Assert().Equal([]string{"request info", "db trace", "context db trace"}, messages[:3], "The TRACE level of the second stream should be kept")
+}
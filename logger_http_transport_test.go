@@ -0,0 +1,180 @@
+package logger_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type HttpTransportSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestHttpTransportSuite(t *testing.T) {
+	suite.Run(t, new(HttpTransportSuite))
+}
+
+func (suite *HttpTransportSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *HttpTransportSuite) TestCanLogRequests() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	client := &http.Client{Transport: log.HttpTransport(nil)}
+	res, err := client.Get(server.URL + "/api/users?token=secret&page=2")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(http.StatusOK, res.StatusCode)
+	suite.Assert().Len(stream.Records(), 1, "The finish should be logged when the body is closed")
+	_, _ = io.Copy(io.Discard, res.Body)
+	suite.Require().NoError(res.Body.Close())
+	suite.Require().NoError(res.Body.Close())
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	for _, record := range records {
+		suite.Assert().Equal("http_client", record.Get("topic"))
+		suite.Assert().Equal("/api/users", record.Get("scope"))
+		suite.Assert().Equal("GET", record.Get("verb"))
+		suite.Assert().Equal(server.URL+"/api/users?page=REDACTED&token=REDACTED", record.Get("url"), "The query values should be redacted")
+		suite.Assert().NotEmpty(record.Get("reqid"))
+		suite.Assert().NotContains(record.Get("msg"), "secret")
+	}
+	suite.Assert().Equal(logger.INFO, records[0].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[0].Get("msg").(string), "⏳ request start: GET "))
+	suite.Assert().Equal(logger.INFO, records[1].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[1].Get("msg").(string), "✅ request finish: GET "))
+	suite.Assert().Equal(http.StatusOK, records[1].Get("http_status"))
+	suite.Assert().Equal(int64(5), records[1].Get("received"))
+	suite.Assert().NotNil(records[1].Get("duration"))
+}
+
+func (suite *HttpTransportSuite) TestCanLogFailedRequests() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	stream := &RecordingStream{}
+	client := &http.Client{Transport: logger.Create("test", stream).HttpTransport(http.DefaultTransport)}
+	res, err := client.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	suite.Require().NoError(err)
+	_ = res.Body.Close()
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(logger.ERROR, records[1].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[1].Get("msg").(string), "❌ request finish: POST "))
+	suite.Assert().Equal(http.StatusNotFound, records[1].Get("http_status"))
+	suite.Assert().Equal(int64(5), records[1].Get("sent"))
+}
+
+func (suite *HttpTransportSuite) TestCanCountReceivedBytes() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing makes the response chunked, so its length is not known in advance
+		_, _ = w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(" world"))
+	}))
+	defer server.Close()
+
+	stream := &RecordingStream{}
+	client := &http.Client{Transport: logger.Create("test", stream).HttpTransport(nil)}
+	res, err := client.Get(server.URL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(int64(-1), res.ContentLength)
+	body, err := io.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().NoError(res.Body.Close())
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(int64(len(body)), records[1].Get("received"))
+}
+
+func (suite *HttpTransportSuite) TestCanLogTransportErrors() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // so the connection is refused
+
+	stream := &RecordingStream{}
+	client := &http.Client{Transport: logger.Create("test", stream).HttpTransport(nil)}
+	_, err := client.Get(server.URL + "/?password=secret")
+	suite.Require().Error(err)
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(logger.ERROR, records[1].Get("level"))
+	suite.Assert().True(strings.HasPrefix(records[1].Get("msg").(string), "❌ request failed: GET "))
+	suite.Assert().NotNil(records[1].Get("err"))
+	suite.Assert().Nil(records[1].Get("http_status"))
+	suite.Assert().NotContains(records[1].Get("msg"), "secret")
+}
+
+func (suite *HttpTransportSuite) TestCanPropagateRequestID() {
+	var received string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Request-Id")
+	}))
+	defer backend.Close()
+
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	client := &http.Client{Transport: log.HttpTransport(nil)}
+	frontend := log.HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		suite.Require().NoError(err)
+		res, err := client.Do(req)
+		suite.Require().NoError(err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		suite.Assert().Empty(req.Header.Get("X-Request-Id"), "The request should not be modified")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "1234")
+	frontend.ServeHTTP(httptest.NewRecorder(), req)
+
+	suite.Assert().Equal("1234", received, "The request id should be sent to the backend")
+	records := stream.Records()
+	suite.Require().Len(records, 4)
+	suite.Assert().Equal("route", records[0].Get("topic"))
+	for _, record := range records[1:3] {
+		suite.Assert().Equal("http_client", record.Get("topic"))
+		suite.Assert().Equal("1234", record.Get("reqid"))
+		suite.Assert().Equal("/", record.Get("path"), "The Logger of the request context should be used")
+	}
+}
+
+func (suite *HttpTransportSuite) TestCanUseRequestIDFromHeader() {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Correlation-Id")
+	}))
+	defer server.Close()
+
+	stream := &RecordingStream{}
+	client := &http.Client{Transport: logger.Create("test", stream).HttpTransportWithRequestIDHeader(nil, "X-Correlation-Id")}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	suite.Require().NoError(err)
+	req.Header.Set("X-Correlation-Id", "5678")
+	res, err := client.Do(req)
+	suite.Require().NoError(err)
+	_ = res.Body.Close()
+
+	suite.Assert().Equal("5678", received)
+	for _, record := range stream.Records() {
+		suite.Assert().Equal("5678", record.Get("reqid"))
+	}
+}
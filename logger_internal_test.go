@@ -352,13 +352,12 @@ func (suite *InternalLoggerSuite) TestIsHijacker() {
 		statusCode:     200,
 		written:        0,
 	}
-	suite.Assert().Implements((*http.Hijacker)(nil), w)
-	_, _, err := w.Hijack()
+	hijacker, ok := w.wrap().(http.Hijacker)
+	suite.Require().True(ok, "The responseWriter should be a http.Hijacker")
+	_, _, err := hijacker.Hijack()
 	suite.Require().ErrorIs(err, errors.NotImplemented)
 	w.ResponseWriter = &noopResponse{}
-	suite.Assert().Implements((*http.Hijacker)(nil), w)
-	_, _, err = w.Hijack()
-	suite.Require().ErrorIs(err, errors.InvalidType)
+	suite.Assert().NotImplements((*http.Hijacker)(nil), w.wrap(), "The responseWriter should not be a http.Hijacker if the ResponseWriter is not")
 }
 
 type hijackerResponse struct{}
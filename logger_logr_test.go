@@ -0,0 +1,125 @@
+package logger_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gildas/go-errors"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type LogrSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestLogrSuite(t *testing.T) {
+	suite.Run(t, new(LogrSuite))
+}
+
+func (suite *LogrSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *LogrSuite) TestCanMapVLevels() {
+	stream := &RecordingStream{}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	log.Info("info")
+	log.V(1).Info("debug")
+	log.V(2).Info("trace")
+	log.V(5).Info("trace too")
+
+	expected := []logger.Level{logger.INFO, logger.DEBUG, logger.TRACE, logger.TRACE}
+	records := stream.Records()
+	suite.Require().Len(records, len(expected))
+	for i, level := range expected {
+		suite.Assert().Equal(level, records[i].Get("level"), "Record %s has the wrong level", records[i].Get("msg"))
+	}
+}
+
+func (suite *LogrSuite) TestCanFilterWithLevelSet() {
+	levels := logger.NewLevelSet(logger.INFO)
+	levels.Set(logger.DEBUG, "controller", "")
+	stream := &RecordingStream{FilterLevels: levels}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	suite.Assert().True(log.Enabled())
+	suite.Assert().False(log.V(1).Enabled())
+	suite.Assert().True(log.WithName("controller").V(1).Enabled(), "The topic should be used")
+	suite.Assert().False(log.WithName("controller").V(2).Enabled())
+
+	log.V(1).Info("main debug")
+	log.WithName("controller").V(1).Info("controller debug")
+	log.WithName("controller").V(2).Info("controller trace")
+	suite.Assert().Equal([]string{"controller debug"}, stream.Messages())
+}
+
+func (suite *LogrSuite) TestCanWriteWithNames() {
+	stream := &RecordingStream{}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	log.WithName("controller").Info("topic only")
+	log.WithName("controller").WithName("reconciler").WithName("pod").Info("topic and scope")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("controller", records[0].Get("topic"))
+	suite.Assert().Equal("main", records[0].Get("scope"))
+	suite.Assert().Equal("controller", records[1].Get("topic"))
+	suite.Assert().Equal("reconciler/pod", records[1].Get("scope"))
+}
+
+func (suite *LogrSuite) TestCanWriteWithValues() {
+	stream := &RecordingStream{}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	child := log.WithValues("reqid", "1234", "user", "john")
+	child.Info("Hello 100%", "user", "jane", 42, "answer", "missing")
+	log.Info("no values")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("Hello 100%", records[0].Get("msg"), "The message should not be formatted")
+	suite.Assert().Equal("1234", records[0].Get("reqid"))
+	suite.Assert().Equal("jane", records[0].Get("user"), "The values of the call should win")
+	suite.Assert().Equal("answer", records[0].Get("42"), "Keys that are not strings should be converted")
+	suite.Assert().Nil(records[0].Get("missing"), "A key without value should be ignored")
+	suite.Assert().Nil(records[1].Get("reqid"), "The parent logger should not be affected")
+}
+
+func (suite *LogrSuite) TestCanWriteErrors() {
+	stream := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.ERROR)}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	err := errors.NotFound.With("pod", "web-1")
+	log.V(3).Error(err, "Failed to reconcile 100%", "namespace", "default")
+	log.Error(nil, "Failed without error")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(logger.ERROR, records[0].Get("level"), "Errors should be written at ERROR whatever the V-level")
+	suite.Assert().True(strings.HasPrefix(records[0].Get("msg").(string), "Failed to reconcile 100%, Error: "+err.Error()), "The error should be added to the message like Errorf does")
+	suite.Assert().Equal(err, records[0].Get("err"), "The error should be written like Errorf does")
+	suite.Assert().Equal("default", records[0].Get("namespace"))
+	suite.Assert().Equal("Failed without error", records[1].Get("msg"))
+	suite.Assert().Nil(records[1].Get("err"))
+}
+
+func (suite *LogrSuite) TestCanWriteSourceInfo() {
+	stream := &RecordingStream{SourceInfo: true}
+	log := logr.New(logger.Create("test", stream).AsLogr())
+	log.Info("info")
+	log.Error(errors.NotImplemented, "error")
+	helper := func(log logr.Logger) {
+		log.WithCallDepth(1).Info("from helper")
+	}
+	helper(log)
+	logger.Create("test", stream).Errorf("errorf", errors.NotImplemented)
+
+	records := stream.Records()
+	suite.Require().Len(records, 4)
+	for _, record := range records {
+		suite.Assert().Equal("logger_logr_test.go", record.Get("file"), "Record %s has the wrong file", record.Get("msg"))
+		suite.Assert().Equal("(*LogrSuite).TestCanWriteSourceInfo", record.Get("func"), "Record %s has the wrong func", record.Get("msg"))
+	}
+}
@@ -0,0 +1,173 @@
+package logger_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type SlogHandlerSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestSlogHandlerSuite(t *testing.T) {
+	suite.Run(t, new(SlogHandlerSuite))
+}
+
+func (suite *SlogHandlerSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *SlogHandlerSuite) TestCanWriteThroughSlog() {
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	slogger := slog.New(log.AsSlogHandler())
+	slogger.Info("Hello World", "user", "john", "count", 2)
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("Hello World", records[0].Get("msg"))
+	suite.Assert().Equal(logger.INFO, records[0].Get("level"))
+	suite.Assert().Equal("john", records[0].Get("user"))
+	suite.Assert().Equal(int64(2), records[0].Get("count"))
+	suite.Assert().Equal("test", records[0].Get("name"))
+	suite.Assert().Equal("main", records[0].Get("topic"))
+	suite.Assert().NotNil(records[0].Get("time"))
+}
+
+func (suite *SlogHandlerSuite) TestCanMapLevels() {
+	stream := &RecordingStream{}
+	slogger := slog.New(logger.Create("test", stream).AsSlogHandler())
+	ctx := context.Background()
+	slogger.Log(ctx, slog.LevelDebug-4, "trace")
+	slogger.Debug("debug")
+	slogger.Info("info")
+	slogger.Warn("warn")
+	slogger.Error("error")
+	slogger.Log(ctx, slog.LevelError+4, "fatal")
+
+	expected := []logger.Level{logger.TRACE, logger.DEBUG, logger.INFO, logger.WARN, logger.ERROR, logger.FATAL}
+	records := stream.Records()
+	suite.Require().Len(records, len(expected))
+	for i, level := range expected {
+		suite.Assert().Equal(level, records[i].Get("level"), "Record %s has the wrong level", records[i].Get("msg"))
+	}
+}
+
+func (suite *SlogHandlerSuite) TestCanFilterWithLevelSet() {
+	levels := logger.NewLevelSet(logger.INFO)
+	levels.Set(logger.DEBUG, "http", "")
+	stream := &RecordingStream{FilterLevels: levels}
+	log := logger.Create("test", stream)
+	ctx := context.Background()
+
+	handler := log.AsSlogHandler()
+	suite.Assert().False(handler.Enabled(ctx, slog.LevelDebug))
+	suite.Assert().True(handler.Enabled(ctx, slog.LevelInfo))
+	suite.Assert().True(log.Topic("http").AsSlogHandler().Enabled(ctx, slog.LevelDebug), "The topic of the Logger should be used")
+	suite.Assert().True(handler.WithAttrs([]slog.Attr{slog.String("topic", "http")}).Enabled(ctx, slog.LevelDebug), "The topic attribute should be used")
+
+	slogger := slog.New(handler)
+	slogger.Debug("main debug")
+	slogger.With("topic", "http").Debug("http debug")
+	slogger.Info("main info")
+	slogger.With("topic", "http").Debug("record debug", "topic", "main")
+	suite.Assert().Equal([]string{"http debug", "main info"}, stream.Messages(), "The topic attribute of the record should be used")
+}
+
+func (suite *SlogHandlerSuite) TestShouldNotOverwriteRecordFields() {
+	stream := &RecordingStream{}
+	slogger := slog.New(logger.Create("test", stream).AsSlogHandler())
+	slogger.With("level", "debug").Warn("Hello", "msg", "World", "time", "now")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("Hello", records[0].Get("msg"))
+	suite.Assert().Equal(logger.WARN, records[0].Get("level"))
+	suite.Assert().IsType(time.Time{}, records[0].Get("time"))
+	suite.Assert().Equal("World", records[0].Get("fields.msg"))
+	suite.Assert().Equal("debug", records[0].Get("fields.level"))
+	suite.Assert().Equal("now", records[0].Get("fields.time"))
+}
+
+func (suite *SlogHandlerSuite) TestCanWriteWithAttrs() {
+	stream := &RecordingStream{}
+	slogger := slog.New(logger.Create("test", stream).AsSlogHandler())
+	child := slogger.With("reqid", "1234", "user", "john")
+	child.Info("first", "user", "jane")
+	slogger.Info("second")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("1234", records[0].Get("reqid"))
+	suite.Assert().Equal("jane", records[0].Get("user"), "The attributes of the Record should win")
+	suite.Assert().Nil(records[1].Get("reqid"), "The parent handler should not be affected")
+}
+
+func (suite *SlogHandlerSuite) TestCanWriteGroups() {
+	stream := &RecordingStream{}
+	slogger := slog.New(logger.Create("test", stream).AsSlogHandler())
+	slogger.
+		With("app", "myapp").
+		WithGroup("request").
+		With("method", "GET").
+		WithGroup("url").
+		Info("request", "path", "/", slog.Group("query", "page", 2), slog.Group("", "inlined", true))
+	slogger.WithGroup("empty").Info("no attributes")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("myapp", records[0].Get("app"))
+	payload, err := json.Marshal(records[0].Get("request"))
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"method": "GET", "url": {"path": "/", "inlined": true, "query": {"page": 2}}}`, string(payload))
+	suite.Assert().Nil(records[1].Get("empty"), "Empty groups should be omitted")
+}
+
+func (suite *SlogHandlerSuite) TestCanWriteSourceInfo() {
+	stream := &RecordingStream{SourceInfo: true}
+	slogger := slog.New(logger.Create("test", stream).AsSlogHandler())
+	slogger.Info("Hello")
+
+	records := stream.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("logger_slog_test.go", records[0].Get("file"))
+	suite.Assert().Equal("(*SlogHandlerSuite).TestCanWriteSourceInfo", records[0].Get("func"))
+	suite.Assert().Equal("github.com/gildas/go-logger_test", records[0].Get("package"))
+}
+
+func (suite *SlogHandlerSuite) TestCanRedactMessages() {
+	stream := &RecordingStream{}
+	redactor, err := logger.NewRedactor(`\d{4}-\d{4}`)
+	suite.Require().NoError(err)
+	log := logger.Create("test", stream, redactor)
+	slog.New(log.AsSlogHandler()).Info("card 1234-5678")
+	suite.Assert().Equal([]string{"card REDACTED"}, stream.Messages())
+}
+
+func (suite *SlogHandlerSuite) TestCanPassSlogTests() {
+	stream := &RecordingStream{}
+	handler := logger.Create("test", stream).AsSlogHandler()
+	err := slogtest.TestHandler(handler, func() []map[string]any {
+		results := []map[string]any{}
+		for _, record := range stream.Records() {
+			payload, err := record.MarshalJSON()
+			suite.Require().NoError(err)
+			result := map[string]any{}
+			suite.Require().NoError(json.Unmarshal(payload, &result))
+			results = append(results, result)
+		}
+		return results
+	})
+	suite.Require().NoError(err)
+}
@@ -0,0 +1,123 @@
+package logger_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gildas/go-logger"
+)
+
+type TraceSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestTraceSuite(t *testing.T) {
+	suite.Run(t, new(TraceSuite))
+}
+
+func (suite *TraceSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *TraceSuite) spanContext() trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	suite.Require().NoError(err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	suite.Require().NoError(err)
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+}
+
+func (suite *TraceSuite) TestCanWriteSpanFromContext() {
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	ctx := trace.ContextWithSpanContext(context.Background(), suite.spanContext())
+	log.WithContext(ctx).Infof("with span")
+	log.Infof("without span")
+
+	records := stream.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", records[0].Get("trace_id"))
+	suite.Assert().Equal("00f067aa0ba902b7", records[0].Get("span_id"))
+	suite.Assert().Equal("01", records[0].Get("trace_flags"))
+	suite.Assert().Nil(records[1].Get("trace_id"), "The Logger should not be modified")
+}
+
+func (suite *TraceSuite) TestShouldNotModifyLoggerWithoutSpan() {
+	log := logger.Create("test", &RecordingStream{})
+	suite.Assert().Same(log, log.WithContext(context.Background()))
+	suite.Assert().Same(log, log.WithContext(nil)) //nolint:staticcheck
+}
+
+func (suite *TraceSuite) TestCanPropagateTraceParent() {
+	var traceparent, tracestate string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		tracestate = r.Header.Get("tracestate")
+	}))
+	defer backend.Close()
+
+	stream := &RecordingStream{}
+	log := logger.Create("test", stream)
+	client := &http.Client{Transport: log.HttpTransport(nil)}
+	frontend := log.HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanContext := trace.SpanContextFromContext(r.Context())
+		suite.Assert().True(spanContext.IsRemote(), "The trace context should be stored in the request context")
+		suite.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+		logger.Must(logger.FromContext(r.Context())).Infof("in handler")
+
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		suite.Require().NoError(err)
+		res, err := client.Do(req)
+		suite.Require().NoError(err)
+		_ = res.Body.Close()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	frontend.ServeHTTP(httptest.NewRecorder(), req)
+
+	suite.Assert().Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceparent)
+	suite.Assert().Equal("vendor=value", tracestate)
+	records := stream.Records()
+	suite.Require().Len(records, 5)
+	for _, record := range records {
+		suite.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", record.Get("trace_id"), "Record %s should have the trace id", record.Get("msg"))
+		suite.Assert().Equal("00f067aa0ba902b7", record.Get("span_id"))
+		suite.Assert().Equal("01", record.Get("trace_flags"))
+	}
+}
+
+func (suite *TraceSuite) TestShouldIgnoreInvalidTraceParent() {
+	stream := &RecordingStream{}
+	handler := logger.Create("test", stream).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Assert().False(trace.SpanContextFromContext(r.Context()).IsValid())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, record := range stream.Records() {
+		suite.Assert().Nil(record.Get("trace_id"))
+	}
+}
+
+func (suite *TraceSuite) TestShouldKeepActiveSpan() {
+	stream := &RecordingStream{}
+	handler := logger.Create("test", stream).HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-11111111111111111111111111111111-1111111111111111-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(trace.ContextWithSpanContext(req.Context(), suite.spanContext())))
+
+	for _, record := range stream.Records() {
+		suite.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", record.Get("trace_id"), "The span of an OpenTelemetry middleware should win")
+	}
+}
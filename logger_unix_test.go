@@ -0,0 +1,77 @@
+//go:build !windows
+// +build !windows
+
+package logger_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/gildas/go-logger"
+)
+
+func (suite *LoggerSuite) TestCanReopenOnSignal() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	path := filepath.Join(folder, "test.log")
+	log := logger.Create("test", &logger.FileStream{Path: path, Unbuffered: true})
+	defer log.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log.HandleReopenSignal(ctx, syscall.SIGUSR1)
+
+	log.Infof("before")
+	moved := filepath.Join(folder, "test.log.1")
+	suite.Require().NoError(os.Rename(path, moved))
+	suite.Require().NoError(syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	suite.Require().Eventually(func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "The file should have been reopened")
+	log.Child("child", nil).Infof("after")
+
+	content, err := os.ReadFile(moved)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(content), `"msg":"before"`)
+	content, err = os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(string(content), `"msg":"after"`)
+}
+
+func (suite *LoggerSuite) TestCanChangeLevelsOnSignal() {
+	stream := &logger.WriterStream{Writer: io.Discard, Unbuffered: true, FilterLevels: logger.NewLevelSet(logger.INFO)}
+	recorder := &RecordingStream{}
+	log := logger.Create("test", stream, recorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log.HandleLevelSignals(ctx, logger.LevelSignalOptions{})
+
+	suite.Require().NoError(syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	suite.Require().Eventually(func() bool { return len(recorder.Messages()) == 1 }, time.Second, 10*time.Millisecond, "The new levels should have been logged")
+	// The levels are read only from the records, as the stream is changed by another goroutine
+	suite.Assert().Contains(recorder.Messages()[0], "the levels are now DEBUG, TRACE", "The levels of each stream should be logged")
+	suite.Assert().Equal(logger.WARN, recorder.Records()[0].Get("level"))
+
+	suite.Require().NoError(syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	suite.Require().Eventually(func() bool { return len(recorder.Messages()) == 2 }, time.Second, 10*time.Millisecond, "The new levels should have been logged")
+	suite.Assert().Contains(recorder.Messages()[1], "the levels are now INFO, TRACE")
+	suite.Assert().Equal(logger.INFO, stream.FilterLevels.GetDefault())
+}
+
+func (suite *LoggerSuite) TestCanChangeTopicLevelOnSignal() {
+	stream := &logger.WriterStream{Writer: io.Discard, Unbuffered: true, FilterLevels: logger.NewLevelSet(logger.INFO)}
+	recorder := &RecordingStream{}
+	log := logger.Create("test", stream, recorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log.HandleLevelSignals(ctx, logger.LevelSignalOptions{FilterLessSignal: syscall.SIGHUP, Topic: "db"})
+
+	suite.Require().NoError(syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	suite.Require().Eventually(func() bool { return len(recorder.Messages()) == 1 }, time.Second, 10*time.Millisecond, "The new levels should have been logged")
+	suite.Assert().Equal(logger.DEBUG, stream.FilterLevels.Get("db", "query"))
+	suite.Assert().Equal(logger.INFO, stream.FilterLevels.GetDefault(), "The default level should not change")
+}
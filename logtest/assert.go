@@ -0,0 +1,113 @@
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gildas/go-logger"
+)
+
+// Find gets the captured records that match the given level, message pattern and fields
+//
+// If level is logger.UNSET, records of any level match.
+//
+// msgPattern is a regular expression the "msg" of the records must match, an empty pattern matches all messages.
+//
+// Each field must be present in the records with an equal value.
+// Values are equal if they are deeply equal or if they are formatted the same way (e.g.: 200 and "200").
+func (stream *CaptureStream) Find(level logger.Level, msgPattern string, fields map[string]any) ([]*logger.Record, error) {
+	pattern, err := regexp.Compile(msgPattern)
+	if err != nil {
+		return nil, err
+	}
+	found := []*logger.Record{}
+	for _, record := range stream.Records() {
+		if matches(record, level, pattern, fields) {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+// AssertLogged asserts that at least one captured Record matches the given level, message pattern and fields
+//
+// See Find for the matching rules.
+//
+// When the assertion fails, the captured records are shown.
+func (stream *CaptureStream) AssertLogged(t testing.TB, level logger.Level, msgPattern string, fields map[string]any) bool {
+	t.Helper()
+	found, err := stream.Find(level, msgPattern, fields)
+	if err != nil {
+		t.Errorf("Invalid message pattern %q: %s", msgPattern, err)
+		return false
+	}
+	if len(found) == 0 {
+		t.Errorf("No record matches %s\nCaptured records:\n%s", describe(level, msgPattern, fields), dump(stream.Records()))
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged asserts that no captured Record matches the given level, message pattern and fields
+//
+// See Find for the matching rules.
+//
+// When the assertion fails, the matching records are shown.
+func (stream *CaptureStream) AssertNotLogged(t testing.TB, level logger.Level, msgPattern string, fields map[string]any) bool {
+	t.Helper()
+	found, err := stream.Find(level, msgPattern, fields)
+	if err != nil {
+		t.Errorf("Invalid message pattern %q: %s", msgPattern, err)
+		return false
+	}
+	if len(found) > 0 {
+		t.Errorf("%d record(s) match %s\nMatching records:\n%s", len(found), describe(level, msgPattern, fields), dump(found))
+		return false
+	}
+	return true
+}
+
+func matches(record *logger.Record, level logger.Level, pattern *regexp.Regexp, fields map[string]any) bool {
+	if level != logger.UNSET && logger.GetLevelFromRecord(record) != level {
+		return false
+	}
+	if message, _ := record.Get("msg").(string); !pattern.MatchString(message) {
+		return false
+	}
+	for key, expected := range fields {
+		actual, found := record.Find(key)
+		if !found {
+			return false
+		}
+		if !reflect.DeepEqual(actual, expected) && fmt.Sprint(actual) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(level logger.Level, msgPattern string, fields map[string]any) string {
+	description := fmt.Sprintf("level: %s, msg: /%s/", level, msgPattern)
+	if len(fields) > 0 {
+		description += fmt.Sprintf(", fields: %v", fields)
+	}
+	return description
+}
+
+func dump(records []*logger.Record) string {
+	if len(records) == 0 {
+		return "  (none)"
+	}
+	lines := make([]string, 0, len(records))
+	for _, record := range records {
+		if payload, err := record.MarshalJSON(); err == nil {
+			lines = append(lines, "  "+string(payload))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %v", record.Data))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
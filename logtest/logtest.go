@@ -0,0 +1,31 @@
+// Package logtest helps testing the code that logs with github.com/gildas/go-logger
+//
+// Instead of capturing stdout and parsing the JSON, tests log to a CaptureStream and assert on the records:
+//
+//	func TestSomething(t *testing.T) {
+//		log, capture := logtest.NewTestLogger(t)
+//		DoSomething(log)
+//		capture.AssertLogged(t, logger.INFO, `^Something done`, map[string]any{"count": 2})
+//		capture.AssertNotLogged(t, logger.ERROR, "", nil)
+//	}
+package logtest
+
+import (
+	"testing"
+
+	"github.com/gildas/go-logger"
+)
+
+// NewTestLogger creates a Logger that writes all its records to a CaptureStream
+//
+// The records are also written to t.Log, so they are shown inline when the test fails,
+// until the test completes.
+//
+// The Logger is named after the test.
+func NewTestLogger(t testing.TB) (*logger.Logger, *CaptureStream) {
+	t.Helper()
+	stream := &CaptureStream{FilterLevels: logger.NewLevelSet(logger.TRACE)}
+	stream.attach(t)
+	t.Cleanup(stream.detach)
+	return logger.Create(t.Name(), stream), stream
+}
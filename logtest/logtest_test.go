@@ -0,0 +1,192 @@
+package logtest_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-logger/logtest"
+)
+
+type LogTestSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestLogTestSuite(t *testing.T) {
+	suite.Run(t, new(LogTestSuite))
+}
+
+func (suite *LogTestSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// FakeT records the failures of the assertions instead of failing the test
+type FakeT struct {
+	testing.TB
+	failures []string
+	logs     []string
+	cleanups []func()
+	mutex    sync.Mutex
+}
+
+func (t *FakeT) Helper() {}
+
+func (t *FakeT) Name() string { return "FakeT" }
+
+func (t *FakeT) Cleanup(cleanup func()) {
+	t.cleanups = append(t.cleanups, cleanup)
+}
+
+// complete runs the cleanups, like the testing package does when a test completes
+func (t *FakeT) complete() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func (t *FakeT) Errorf(format string, args ...any) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *FakeT) Log(args ...any) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.logs = append(t.logs, fmt.Sprint(args...))
+}
+
+func (t *FakeT) Logf(format string, args ...any) {
+	t.Log(fmt.Sprintf(format, args...))
+}
+
+func (suite *LogTestSuite) TestCanAssertLogged() {
+	log, capture := logtest.NewTestLogger(suite.T())
+	log.Record("user", "john").Infof("User %s logged in", "john")
+	log.Record("status", 200).Debugf("request done")
+
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.INFO, `^User \w+ logged in$`, map[string]any{"user": "john"}))
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.DEBUG, "done", map[string]any{"status": 200}))
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.DEBUG, "done", map[string]any{"status": "200"}), "Values should be compared by their format as well")
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.UNSET, "", nil), "UNSET should match any level")
+	suite.Assert().True(capture.AssertNotLogged(suite.T(), logger.ERROR, "", nil))
+}
+
+func (suite *LogTestSuite) TestFailsAssertLogged() {
+	log, capture := logtest.NewTestLogger(suite.T())
+	log.Record("user", "john").Infof("User logged in")
+
+	t := &FakeT{}
+	suite.Assert().False(capture.AssertLogged(t, logger.WARN, "logged in", nil))
+	suite.Assert().False(capture.AssertLogged(t, logger.INFO, "logged out", nil))
+	suite.Assert().False(capture.AssertLogged(t, logger.INFO, "logged in", map[string]any{"user": "jane"}))
+	suite.Assert().False(capture.AssertLogged(t, logger.INFO, "logged in", map[string]any{"role": "admin"}))
+	suite.Assert().False(capture.AssertLogged(t, logger.INFO, "(", nil), "Invalid patterns should fail")
+	suite.Assert().False(capture.AssertNotLogged(t, logger.INFO, "logged in", nil))
+	suite.Require().Len(t.failures, 6)
+	suite.Assert().Contains(t.failures[0], "No record matches level: WARN, msg: /logged in/")
+	suite.Assert().Contains(t.failures[0], `"msg":"User logged in"`, "The captured records should be shown")
+	suite.Assert().Contains(t.failures[5], "1 record(s) match")
+}
+
+func (suite *LogTestSuite) TestCanFilterRecords() {
+	log, capture := logtest.NewTestLogger(suite.T())
+	log.Infof("main")
+	log.Child("http", "request").Infof("http request")
+	log.Child("http", "response").Infof("http response")
+	log.Child("db", nil).Infof("db")
+
+	suite.Assert().Equal(4, capture.Len())
+	suite.Assert().Equal([]string{"main", "http request", "http response", "db"}, capture.Messages())
+	suite.Assert().Equal([]string{"http request", "http response"}, capture.Messages("http"))
+	suite.Assert().Equal([]string{"http response"}, capture.Messages("http", "response"))
+	suite.Assert().Len(capture.Records("db"), 1)
+	suite.Assert().Empty(capture.Records("nope"))
+
+	capture.Reset()
+	suite.Assert().Zero(capture.Len())
+}
+
+func (suite *LogTestSuite) TestCanMirrorRecordsToTest() {
+	t := &FakeT{}
+	log, capture := logtest.NewTestLogger(t)
+	log.Infof("Hello World")
+	suite.Require().Len(t.logs, 1)
+	suite.Assert().Contains(t.logs[0], `"msg":"Hello World"`)
+	suite.Assert().Contains(t.logs[0], `"name":"FakeT"`, "The logger should be named after the test")
+	suite.Assert().Equal(1, capture.Len())
+}
+
+func (suite *LogTestSuite) TestShouldStopMirroringRecordsWhenTestCompletes() {
+	t := &FakeT{}
+	log, _ := logtest.NewTestLogger(t)
+	child := log.Child("child", nil, logger.NewLevelSet(logger.TRACE))
+	child.Infof("before")
+	suite.Require().Len(t.logs, 1)
+
+	t.complete()
+	log.Infof("after")
+	child.Infof("after")
+	suite.Assert().Len(t.logs, 1, "The clones of the stream should be detached as well")
+}
+
+func (suite *LogTestSuite) TestCanCaptureRecordsOfChildren() {
+	log, capture := logtest.NewTestLogger(suite.T())
+	log.Infof("parent")
+	child := log.Child("child", nil, logger.NewLevelSet(logger.TRACE))
+	child.Debugf("child")
+	suite.Assert().Equal([]string{"parent", "child"}, capture.Messages())
+	suite.Assert().Equal([]string{"child"}, capture.Messages("child"))
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.DEBUG, "^child$", nil))
+}
+
+func (suite *LogTestSuite) TestShouldNotConvertRecordsFromEnvironment() {
+	suite.T().Setenv("LOG_CONVERTER", "stackdriver")
+	log, capture := logtest.NewTestLogger(suite.T())
+	log.Infof("Hello")
+	suite.Assert().True(capture.AssertLogged(suite.T(), logger.INFO, "^Hello$", nil))
+}
+
+func (suite *LogTestSuite) TestCanKeepConvertedRecords() {
+	capture := &logtest.CaptureStream{Converter: &logger.StackDriverConverter{}}
+	log := logger.Create("test", capture)
+	log.Warnf("Hello")
+	records := capture.Records()
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal("Hello", records[0].Get("message"), "The record should have been converted")
+	suite.Assert().NotNil(records[0].Get("severity"))
+}
+
+func (suite *LogTestSuite) TestCanKeepRecordsAfterLoggerReusesThem() {
+	log, capture := logtest.NewTestLogger(suite.T())
+	for i := 0; i < 10; i++ {
+		log.Infof("message %d", i)
+	}
+	for i, message := range capture.Messages() {
+		suite.Assert().Equal(fmt.Sprintf("message %d", i), message)
+	}
+}
+
+func (suite *LogTestSuite) TestCanCloneAndSetFilterLevel() {
+	capture := &logtest.CaptureStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	suite.Assert().Equal("Stream to capture, Filter: INFO", capture.String())
+	capture.SetFilterLevel(logger.DEBUG, "main")
+	suite.Assert().True(capture.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(capture.ShouldWrite(logger.DEBUG, "other", ""))
+	capture.FilterMore()
+	suite.Assert().Equal(logger.WARN, capture.FilterLevels.GetDefault())
+
+	suite.Require().NoError(capture.Write(logger.NewRecord().Set("msg", "Hello")))
+	clone := capture.Clone().(*logtest.CaptureStream)
+	suite.Assert().Equal(1, clone.Len(), "Records should be shared with the clone")
+	suite.Require().NoError(clone.Write(logger.NewRecord().Set("msg", "World")))
+	suite.Assert().Equal([]string{"Hello", "World"}, capture.Messages(), "Records of the clone should be shared with the original stream")
+	capture.Close()
+	suite.Assert().Equal(2, capture.Len(), "Records should be kept after the stream is closed")
+}
@@ -0,0 +1,240 @@
+package logtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gildas/go-logger"
+)
+
+// CaptureStream is the Stream that keeps the records it writes in memory, so tests can inspect them
+//
+// The records are converted with the Converter (default: logger.BunyanConverter) before being kept.
+// The LOG_CONVERTER environment variable is not used, as some converters remove the "msg" the assertions look at.
+//
+// If FilterLevels is empty, all records are kept.
+//
+// The stream and its clones (e.g.: the streams of the children of a Logger) keep their records together.
+type CaptureStream struct {
+	Converter    logger.Converter
+	FilterLevels logger.LevelSet
+	SourceInfo   bool
+	captured     *captured
+	mutex        sync.RWMutex
+}
+
+// captured holds the records of a CaptureStream and its clones, and the test they are written to
+type captured struct {
+	records []*logger.Record
+	t       testing.TB
+	mutex   sync.RWMutex
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *CaptureStream) GetFilterLevels() logger.LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *CaptureStream) SetFilterLevel(level logger.Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// implements logger.FilterModifier
+func (stream *CaptureStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// implements logger.FilterModifier
+func (stream *CaptureStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *CaptureStream) Write(record *logger.Record) error {
+	stream.mutex.Lock()
+	if stream.Converter == nil {
+		stream.Converter = &logger.BunyanConverter{}
+	}
+	converter := stream.Converter
+	stream.mutex.Unlock()
+
+	// the Logger reuses its records, we must keep a copy
+	record = converter.Convert(record.Clone())
+	captured := stream.storage()
+	// the storage stays locked while logging, so the test cannot complete in the meantime
+	captured.mutex.Lock()
+	defer captured.mutex.Unlock()
+	captured.records = append(captured.records, record)
+	if captured.t != nil {
+		if payload, err := record.MarshalJSON(); err == nil {
+			captured.t.Log(string(payload))
+		} else {
+			captured.t.Logf("%s (%s)", record.Get("msg"), err)
+		}
+	}
+	return nil
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *CaptureStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *CaptureStream) ShouldWrite(level logger.Level, topic, scope string) bool {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return level.ShouldWrite(stream.FilterLevels.Get(topic, scope))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *CaptureStream) Flush() {
+}
+
+// Close closes the stream
+//
+// The captured records are kept so they can still be inspected.
+//
+// implements logger.Streamer
+func (stream *CaptureStream) Close() {
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The captured records are shared with the clone, so the records of both streams are found in each of them.
+//
+// implements logger.Streamer
+func (stream *CaptureStream) Clone() logger.Streamer {
+	captured := stream.storage()
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return &CaptureStream{
+		Converter:    stream.Converter,
+		FilterLevels: stream.FilterLevels.Clone(),
+		SourceInfo:   stream.SourceInfo,
+		captured:     captured,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *CaptureStream) String() string {
+	if len(stream.FilterLevels) > 0 {
+		return fmt.Sprintf("Stream to capture, Filter: %s", stream.FilterLevels)
+	}
+	return "Stream to capture"
+}
+
+// Records gets the captured records
+//
+// If present, the first parameter is the topic the records must have.
+//
+// If present, the second parameter is the scope the records must have.
+func (stream *CaptureStream) Records(parameters ...string) []*logger.Record {
+	captured := stream.storage()
+	captured.mutex.RLock()
+	defer captured.mutex.RUnlock()
+	records := make([]*logger.Record, 0, len(captured.records))
+	for _, record := range captured.records {
+		if len(parameters) > 0 && record.Get("topic") != parameters[0] {
+			continue
+		}
+		if len(parameters) > 1 && record.Get("scope") != parameters[1] {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Messages gets the messages of the captured records
+//
+// The parameters are the same as Records.
+func (stream *CaptureStream) Messages(parameters ...string) []string {
+	messages := []string{}
+	for _, record := range stream.Records(parameters...) {
+		if message, ok := record.Get("msg").(string); ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Len tells how many records were captured
+func (stream *CaptureStream) Len() int {
+	captured := stream.storage()
+	captured.mutex.RLock()
+	defer captured.mutex.RUnlock()
+	return len(captured.records)
+}
+
+// Reset discards the captured records, of the stream and its clones
+func (stream *CaptureStream) Reset() {
+	captured := stream.storage()
+	captured.mutex.Lock()
+	defer captured.mutex.Unlock()
+	captured.records = nil
+}
+
+// storage gets the records shared by the stream and its clones
+func (stream *CaptureStream) storage() *captured {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.captured == nil {
+		stream.captured = &captured{}
+	}
+	return stream.captured
+}
+
+// attach writes the records of the stream and its clones to the given test, until detach is called
+func (stream *CaptureStream) attach(t testing.TB) {
+	captured := stream.storage()
+	captured.mutex.Lock()
+	defer captured.mutex.Unlock()
+	captured.t = t
+}
+
+// detach stops writing the records of the stream and its clones to the test
+//
+// testing.TB panics when Log is called after the test completed.
+func (stream *CaptureStream) detach() {
+	captured := stream.storage()
+	captured.mutex.Lock()
+	defer captured.mutex.Unlock()
+	captured.t = nil
+}
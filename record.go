@@ -7,6 +7,7 @@ import (
 	"maps"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gildas/go-errors"
 	"github.com/google/uuid"
@@ -18,6 +19,7 @@ import (
 type Record struct {
 	Data         map[string]any
 	KeysToRedact []string
+	template     string // the format of the message, before it was formatted with its arguments
 }
 
 // NewRecord creates a new empty record
@@ -34,6 +36,7 @@ func (record *Record) Reset() {
 		delete(record.Data, key)
 	}
 	record.KeysToRedact = nil
+	record.template = ""
 }
 
 // NewPooledRecord creates a new empty record
@@ -54,9 +57,19 @@ func (record Record) Clone() *Record {
 	return &Record{
 		Data:         newData,
 		KeysToRedact: append([]string(nil), record.KeysToRedact...),
+		template:     record.template,
 	}
 }
 
+// messageTemplate gets the format of the message if it is known, the message otherwise
+func (record *Record) messageTemplate() string {
+	if len(record.template) > 0 {
+		return record.template
+	}
+	message, _ := record.Get("msg").(string)
+	return message
+}
+
 // Find gets the value at a key
 func (record *Record) Find(key string) (value any, found bool) {
 	if record == nil {
@@ -281,6 +294,35 @@ func jsonValue(object any, buffer *bytes.Buffer, keyToRedact ...string) {
 	}
 }
 
+// stringValue converts a Record value to a string
+//
+// funcs are called, Redactable values are redacted, and values that are not strings are marshaled as JSON
+func stringValue(raw any, keysToRedact []string) string {
+	if value, ok := raw.(func() any); ok {
+		raw = value()
+	}
+	switch value := raw.(type) {
+	case RedactableWithKeys:
+		raw = value.Redact(keysToRedact...)
+	case Redactable:
+		raw = value.Redact()
+	}
+	switch value := raw.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return value.String()
+	}
+	buffer := bufferPool.Get()
+	defer bufferPool.Put(buffer)
+	jsonValue(raw, buffer, keysToRedact...)
+	return buffer.String()
+}
+
 func jsonEscape(value string, buffer *bytes.Buffer) {
 	for _, char := range value {
 		switch char {
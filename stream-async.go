@@ -0,0 +1,369 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// AsyncStream is the Stream that writes to another Stream from a background goroutine
+//
+// Records are cloned and enqueued in a bounded queue of QueueSize records (default: 1024),
+// so the caller does not wait for the Destination to convert, marshal, and write them.
+//
+// When the queue is full, the OverflowPolicy tells what to do with new records (default: block until there is room).
+// The number of dropped records is available via Dropped.
+//
+// Flush and Close wait at most FlushTimeout (default: 5 seconds) for the queue to be drained.
+// The records still in the queue after Close's deadline are dropped.
+type AsyncStream struct {
+	Destination    Streamer
+	QueueSize      int
+	OverflowPolicy OverflowPolicy
+	DropBelowLevel Level
+	FlushTimeout   time.Duration
+	queue          chan *Record
+	closing        chan struct{}
+	done           chan struct{}
+	stopped        chan struct{}
+	closed         bool
+	writers        sync.WaitGroup
+	pending        atomic.Int64
+	dropped        atomic.Uint64
+	waiters        []chan struct{}
+	mutex          sync.Mutex
+}
+
+// OverflowPolicy tells an AsyncStream what to do when its queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until there is room in the queue
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record being written
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest record of the queue to make room for the record being written
+	OverflowDropOldest
+	// OverflowDropBelowLevel drops the record being written if its level is below DropBelowLevel, blocks otherwise
+	OverflowDropBelowLevel
+)
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropBelowLevel:
+		return "drop-below-level"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(policy))
+	}
+}
+
+// GetFilterLevels gets the filter levels of the Destination
+//
+// implements logger.Streamer
+func (stream *AsyncStream) GetFilterLevels() LevelSet {
+	if stream.Destination == nil {
+		return LevelSet{}
+	}
+	return stream.Destination.GetFilterLevels()
+}
+
+// SetFilterLevel sets the filter level of the Destination
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *AsyncStream) SetFilterLevel(level Level, parameters ...string) {
+	if setter, ok := stream.Destination.(FilterSetter); ok {
+		setter.SetFilterLevel(level, parameters...)
+	}
+}
+
+// FilterMore tells the Destination to filter more
+//
+// implements logger.FilterModifier
+func (stream *AsyncStream) FilterMore() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterMore()
+	}
+}
+
+// FilterLess tells the Destination to filter less
+//
+// implements logger.FilterModifier
+func (stream *AsyncStream) FilterLess() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterLess()
+	}
+}
+
+// Write enqueues the given Record
+//
+// The Record is cloned as the caller may reuse it as soon as Write returns.
+//
+// Records that are dropped because of the OverflowPolicy do not return an error.
+//
+// implements logger.Streamer
+func (stream *AsyncStream) Write(record *Record) error {
+	if err := stream.start(); err != nil {
+		return err
+	}
+	defer stream.writers.Done()
+	record = record.Clone()
+
+	stream.pending.Add(1)
+	select {
+	case stream.queue <- record:
+		return nil
+	case <-stream.closing:
+		stream.release(1)
+		return errors.WithStack(errors.RuntimeError.With("stream is closed"))
+	default:
+	}
+
+	// The queue is full
+	switch stream.OverflowPolicy {
+	case OverflowDropNewest:
+		stream.drop()
+		return nil
+	case OverflowDropBelowLevel:
+		if GetLevelFromRecord(record) < stream.DropBelowLevel {
+			stream.drop()
+			return nil
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case stream.queue <- record:
+				return nil
+			case <-stream.closing:
+				stream.release(1)
+				return errors.WithStack(errors.RuntimeError.With("stream is closed"))
+			default:
+			}
+			select {
+			case <-stream.queue:
+				stream.drop()
+			default:
+			}
+		}
+	}
+	select {
+	case stream.queue <- record:
+		return nil
+	case <-stream.closing:
+		stream.release(1)
+		return errors.WithStack(errors.RuntimeError.With("stream is closed"))
+	}
+}
+
+// ShouldLogSourceInfo tells if the Destination logs the source info
+//
+// implements logger.Streamer
+func (stream *AsyncStream) ShouldLogSourceInfo() bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldLogSourceInfo()
+}
+
+// ShouldWrite tells if the given level should be written to the Destination
+//
+// implements logger.Streamer
+func (stream *AsyncStream) ShouldWrite(level Level, topic, scope string) bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldWrite(level, topic, scope)
+}
+
+// Flush waits for the queue to be drained, at most FlushTimeout, and flushes the Destination
+//
+// implements logger.Streamer
+func (stream *AsyncStream) Flush() {
+	stream.mutex.Lock()
+	timeout := stream.FlushTimeout
+	stream.mutex.Unlock()
+	stream.drain(time.Now().Add(timeout))
+	stream.Destination.Flush()
+}
+
+// Close drains the queue, at most FlushTimeout, stops the background goroutine, and closes the Destination
+//
+// Records written once Close has started are rejected.
+// The Destination is closed after the background goroutine has returned.
+//
+// implements logger.Streamer
+func (stream *AsyncStream) Close() {
+	stream.mutex.Lock()
+	if stream.closed {
+		stream.mutex.Unlock()
+		return
+	}
+	stream.closed = true
+	started := stream.queue != nil
+	if started {
+		close(stream.closing)
+	}
+	stream.mutex.Unlock()
+	if started {
+		deadline := time.Now().Add(stream.FlushTimeout)
+		stream.writers.Wait() // no record can be enqueued after this
+		stream.drain(deadline)
+		close(stream.done)
+		<-stream.stopped
+	}
+	if stream.Destination != nil {
+		stream.Destination.Close()
+	}
+}
+
+// Reopen reopens the Destination if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (stream *AsyncStream) Reopen() error {
+	if reopener, ok := stream.Destination.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// Dropped tells how many records were dropped because the queue was full
+func (stream *AsyncStream) Dropped() uint64 {
+	return stream.dropped.Load()
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The Destination is cloned as well.
+//
+// implements logger.Streamer
+func (stream *AsyncStream) Clone() Streamer {
+	return &AsyncStream{
+		Destination:    stream.Destination.Clone(),
+		QueueSize:      stream.QueueSize,
+		OverflowPolicy: stream.OverflowPolicy,
+		DropBelowLevel: stream.DropBelowLevel,
+		FlushTimeout:   stream.FlushTimeout,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *AsyncStream) String() string {
+	return fmt.Sprintf("Async Stream (%s) to %s", stream.OverflowPolicy, stream.Destination)
+}
+
+// start starts the background goroutine if needed and registers a writer
+//
+// The caller must call stream.writers.Done() when it is done writing.
+func (stream *AsyncStream) start() error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.Destination == nil {
+		return errors.ArgumentMissing.With("Destination")
+	}
+	if stream.closed {
+		return errors.WithStack(errors.RuntimeError.With("stream is closed"))
+	}
+	if stream.queue == nil {
+		if stream.QueueSize <= 0 {
+			stream.QueueSize = 1024
+		}
+		if stream.FlushTimeout <= 0 {
+			stream.FlushTimeout = 5 * time.Second
+		}
+		stream.queue = make(chan *Record, stream.QueueSize)
+		stream.closing = make(chan struct{})
+		stream.done = make(chan struct{})
+		stream.stopped = make(chan struct{})
+		go stream.writeJob()
+	}
+	stream.writers.Add(1)
+	return nil
+}
+
+// drain waits for the queue to be empty, at most until the given deadline
+func (stream *AsyncStream) drain(deadline time.Time) {
+	stream.mutex.Lock()
+	if stream.queue == nil || stream.pending.Load() == 0 {
+		stream.mutex.Unlock()
+		return
+	}
+	drained := make(chan struct{})
+	stream.waiters = append(stream.waiters, drained)
+	stream.mutex.Unlock()
+
+	select {
+	case <-drained:
+	case <-time.After(time.Until(deadline)):
+	}
+}
+
+// drop counts a dropped record
+func (stream *AsyncStream) drop() {
+	stream.dropped.Add(1)
+	stream.release(1)
+}
+
+// release removes records from the pending count and wakes up the drain waiters when there are none left
+func (stream *AsyncStream) release(count int64) {
+	if stream.pending.Add(-count) == 0 {
+		stream.mutex.Lock()
+		defer stream.mutex.Unlock()
+		for _, waiter := range stream.waiters {
+			close(waiter)
+		}
+		stream.waiters = nil
+	}
+}
+
+func (stream *AsyncStream) writeJob() {
+	defer close(stream.stopped)
+	write := func(record *Record) {
+		if err := stream.Destination.Write(record); err != nil {
+			reportError(errors.RuntimeError.Wrap(err))
+		}
+		stream.release(1)
+	}
+	// dropAll drops the records that were not written before Close's deadline
+	dropAll := func() {
+		for {
+			select {
+			case <-stream.queue:
+				stream.drop()
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case <-stream.done:
+			dropAll()
+			return
+		default:
+		}
+		select {
+		case record := <-stream.queue:
+			write(record)
+		case <-stream.done:
+			dropAll()
+			return
+		}
+	}
+}
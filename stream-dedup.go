@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// DedupStream is the Stream that collapses consecutive identical records written to another Stream
+//
+// Records are identical when they have the same level, topic, scope, and message ("msg").
+//
+// The first Record is written right away, the identical records that follow within the Window (default: 5 seconds)
+// are counted and collapsed in a single Record with:
+//   - "repeated": the number of collapsed records,
+//   - "first_time": the time of the first Record, the one that was written right away,
+//   - "last_time": the time of the last collapsed Record.
+//
+// That Record is written when the Window closes, when a different Record is written, or when the stream is flushed or closed.
+type DedupStream struct {
+	Destination Streamer
+	Window      time.Duration
+	last        *Record
+	lastKey     dedupKey
+	repeated    int
+	firstTime   time.Time
+	lastTime    time.Time
+	windowEnd   time.Time
+	window      uint64
+	timer       *time.Timer
+	mutex       sync.Mutex
+}
+
+type dedupKey struct {
+	Level   Level
+	Topic   string
+	Scope   string
+	Message string
+}
+
+// GetFilterLevels gets the filter levels of the Destination
+//
+// implements logger.Streamer
+func (stream *DedupStream) GetFilterLevels() LevelSet {
+	if stream.Destination == nil {
+		return LevelSet{}
+	}
+	return stream.Destination.GetFilterLevels()
+}
+
+// SetFilterLevel sets the filter level of the Destination
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *DedupStream) SetFilterLevel(level Level, parameters ...string) {
+	if setter, ok := stream.Destination.(FilterSetter); ok {
+		setter.SetFilterLevel(level, parameters...)
+	}
+}
+
+// FilterMore tells the Destination to filter more
+//
+// implements logger.FilterModifier
+func (stream *DedupStream) FilterMore() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterMore()
+	}
+}
+
+// FilterLess tells the Destination to filter less
+//
+// implements logger.FilterModifier
+func (stream *DedupStream) FilterLess() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterLess()
+	}
+}
+
+// Write writes the given Record unless it is identical to the previous one
+//
+// implements logger.Streamer
+func (stream *DedupStream) Write(record *Record) error {
+	if stream.Destination == nil {
+		return errors.ArgumentMissing.With("Destination")
+	}
+	topic, _ := record.Get("topic").(string)
+	scope, _ := record.Get("scope").(string)
+	message, _ := record.Get("msg").(string)
+	key := dedupKey{Level: GetLevelFromRecord(record), Topic: topic, Scope: scope, Message: message}
+	now := time.Now()
+	stamp, ok := record.Get("time").(time.Time)
+	if !ok {
+		stamp = now.UTC()
+	}
+
+	stream.mutex.Lock()
+	if stream.last != nil && key == stream.lastKey && now.Before(stream.windowEnd) {
+		if stream.repeated == 0 {
+			window := stream.window
+			stream.timer = time.AfterFunc(time.Until(stream.windowEnd), func() { stream.closeWindow(window) })
+		}
+		stream.repeated++
+		stream.lastTime = stamp
+		stream.mutex.Unlock()
+		return nil
+	}
+	summary := stream.summary()
+	if stream.Window <= 0 {
+		stream.Window = 5 * time.Second
+	}
+	stream.last = record.Clone()
+	stream.lastKey = key
+	stream.firstTime = stamp
+	stream.windowEnd = now.Add(stream.Window)
+	stream.window++
+	stream.mutex.Unlock()
+
+	var errs errors.MultiError
+	if summary != nil {
+		errs.Append(stream.Destination.Write(summary))
+	}
+	errs.Append(stream.Destination.Write(record))
+	return errs.AsError()
+}
+
+// ShouldLogSourceInfo tells if the Destination logs the source info
+//
+// implements logger.Streamer
+func (stream *DedupStream) ShouldLogSourceInfo() bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldLogSourceInfo()
+}
+
+// ShouldWrite tells if the given level should be written to the Destination
+//
+// implements logger.Streamer
+func (stream *DedupStream) ShouldWrite(level Level, topic, scope string) bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldWrite(level, topic, scope)
+}
+
+// Flush writes the pending collapsed Record and flushes the Destination
+//
+// implements logger.Streamer
+func (stream *DedupStream) Flush() {
+	stream.mutex.Lock()
+	summary := stream.summary()
+	stream.mutex.Unlock()
+	if summary != nil {
+		if err := stream.Destination.Write(summary); err != nil {
+			reportError(errors.RuntimeError.Wrap(err))
+		}
+	}
+	stream.Destination.Flush()
+}
+
+// Close writes the pending collapsed Record and closes the Destination
+//
+// implements logger.Streamer
+func (stream *DedupStream) Close() {
+	stream.mutex.Lock()
+	summary := stream.summary()
+	stream.mutex.Unlock()
+	if summary != nil {
+		if err := stream.Destination.Write(summary); err != nil {
+			reportError(errors.RuntimeError.Wrap(err))
+		}
+	}
+	stream.Destination.Close()
+}
+
+// Reopen reopens the Destination if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (stream *DedupStream) Reopen() error {
+	if reopener, ok := stream.Destination.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The Destination is cloned as well.
+//
+// implements logger.Streamer
+func (stream *DedupStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &DedupStream{
+		Destination: stream.Destination.Clone(),
+		Window:      stream.Window,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *DedupStream) String() string {
+	return fmt.Sprintf("Dedup Stream (window: %s) to %s", stream.Window, stream.Destination)
+}
+
+// summary gets the collapsed Record of the current window, if any, and ends the window
+//
+// If no Record was collapsed, the window goes on.
+//
+// the caller must hold the mutex
+func (stream *DedupStream) summary() *Record {
+	if stream.last == nil || stream.repeated == 0 {
+		return nil
+	}
+	if stream.timer != nil {
+		stream.timer.Stop()
+		stream.timer = nil
+	}
+	summary := stream.last
+	stream.last = nil
+	summary.Data["time"] = stream.lastTime
+	summary.Data["repeated"] = stream.repeated
+	summary.Data["first_time"] = stream.firstTime
+	summary.Data["last_time"] = stream.lastTime
+	stream.repeated = 0
+	return summary
+}
+
+// closeWindow writes the collapsed Record when the given window closes
+//
+// Stopping the timer does not cancel a call that is already waiting for the mutex,
+// so the call is ignored if another window started since.
+func (stream *DedupStream) closeWindow(window uint64) {
+	stream.mutex.Lock()
+	if window != stream.window {
+		stream.mutex.Unlock()
+		return
+	}
+	summary := stream.summary()
+	stream.mutex.Unlock()
+	if summary != nil {
+		if err := stream.Destination.Write(summary); err != nil {
+			reportError(errors.RuntimeError.Wrap(err))
+		}
+	}
+}
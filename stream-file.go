@@ -2,33 +2,78 @@ package logger
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gildas/go-core"
 	"github.com/gildas/go-errors"
+	"github.com/klauspost/compress/zstd"
 )
 
 // FileStream is the Stream that writes to a file
 //
 //	Any record with a level < FilterLevel will be written
+//
+// The file can be rotated when it grows bigger than MaxSize bytes
+// and/or every RotationInterval (boundaries are computed in UTC).
+//
+// Rotated files are renamed with a timestamp (e.g.: myapp-2021-06-01T15-04-05.000.log),
+// at most MaxBackups of them are kept and the ones older than MaxAge are deleted.
+//
+// If Compression is set ("gzip" or "zstd"), rotated files are compressed in the background.
 type FileStream struct {
 	Path              string
 	Converter         Converter
 	FilterLevels      LevelSet
 	Unbuffered        bool
 	SourceInfo        bool
+	MaxSize           int64
+	RotationInterval  time.Duration
+	MaxBackups        int
+	MaxAge            time.Duration
+	Compression       string
 	file              *os.File
 	output            *bufio.Writer
 	writer            io.Writer
+	size              int64
+	nextRotation      time.Time
+	retryRotation     time.Time
+	compressions      sync.WaitGroup
+	compressionMutex  sync.Mutex
 	flushFrequency    time.Duration
 	environmentPrefix EnvironmentPrefix
 	mutex             sync.Mutex
 }
 
+// rotationRetryDelay is the time to wait before trying again a rotation that failed
+const rotationRetryDelay = time.Minute
+
+// backupTimeFormat is the format used to timestamp rotated files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressionExtensions contains the file extension per supported compression
+var compressionExtensions = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// fileBackup describes a rotated file
+type fileBackup struct {
+	Path      string
+	Timestamp time.Time
+}
+
 // GetFilterLevels gets the filter levels
 //
 // implements logger.Streamer
@@ -90,15 +135,11 @@ func (stream *FileStream) Write(record *Record) (err error) {
 	stream.mutex.Lock()
 	defer stream.mutex.Unlock()
 	if stream.file == nil {
-		const flags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
-		const perms = 0644
-		err = os.MkdirAll(path.Dir(stream.Path), os.ModePerm)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		if stream.file, err = os.OpenFile(stream.Path, flags, perms); err != nil {
-			return errors.WithStack(err)
+		if err = stream.open(); err != nil {
+			return err
 		}
+	}
+	if stream.writer == nil {
 		if stream.Converter == nil {
 			stream.Converter = GetConverterFromEnvironmentWithPrefix(stream.environmentPrefix)
 		}
@@ -115,14 +156,28 @@ func (stream *FileStream) Write(record *Record) (err error) {
 			go stream.flushJob()
 		}
 	}
-	payload, _ := stream.Converter.Convert(record).MarshalJSON()
+	payload, err := JSONEncoder{}.Encode(stream.Converter.Convert(record))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if stream.shouldRotate(int64(len(payload))) {
+		if err = stream.rotate(); err != nil {
+			// The record is written to the current file, the rotation will be tried again later
+			stream.retryRotation = time.Now().Add(rotationRetryDelay)
+			if stream.file == nil {
+				// The file was closed, it is reopened to write the record
+				if openErr := stream.open(); openErr != nil {
+					return err
+				}
+			}
+			reportError(errors.RuntimeError.Wrap(err))
+		}
+	}
 	_, err = stream.writer.Write(payload)
 	if err == nil { // Keep working as long as there is no error
-		_, err = stream.writer.Write([]byte("\n"))
-		if err == nil { // Keep working as long as there is no error
-			if GetLevelFromRecord(record) >= ERROR && stream.output != nil {
-				_ = stream.output.Flush() // calling stream.Flush would Lock the mutex again and end up with a dead-lock
-			}
+		stream.size += int64(len(payload))
+		if GetLevelFromRecord(record) >= ERROR && stream.output != nil {
+			_ = stream.output.Flush() // calling stream.Flush would Lock the mutex again and end up with a dead-lock
 		}
 	}
 	return errors.WithStack(err) // If err is nil, WithStack return nil
@@ -165,6 +220,28 @@ func (stream *FileStream) Close() {
 	if stream.file != nil {
 		_ = stream.file.Close()
 	}
+	stream.compressions.Wait()
+}
+
+// Reopen flushes the stream, closes the file and opens Path again
+//
+// This should be called after an external tool (like logrotate) moved the file.
+// If the stream has not written anything yet, nothing happens.
+//
+// implements logger.Reopener
+func (stream *FileStream) Reopen() (err error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.file == nil {
+		return nil
+	}
+	if stream.output != nil {
+		if err = stream.output.Flush(); err != nil {
+			return errors.WithStack(err) // the buffered data is kept, we will try again later
+		}
+	}
+	_ = stream.file.Close()
+	return stream.open()
 }
 
 // Clone clones the stream, so that the new stream is independent of the original one
@@ -179,6 +256,11 @@ func (stream *FileStream) Clone() Streamer {
 		FilterLevels:      stream.FilterLevels.Clone(),
 		SourceInfo:        stream.SourceInfo,
 		Unbuffered:        stream.Unbuffered,
+		MaxSize:           stream.MaxSize,
+		RotationInterval:  stream.RotationInterval,
+		MaxBackups:        stream.MaxBackups,
+		MaxAge:            stream.MaxAge,
+		Compression:       stream.Compression,
 		environmentPrefix: stream.environmentPrefix,
 	}
 }
@@ -206,3 +288,320 @@ func (stream *FileStream) flushJob() {
 		stream.Flush()
 	}
 }
+
+// open opens the file at Path and computes the rotation state
+//
+// If the stream is already set up, its writer is redirected to the new file.
+//
+// The caller must hold the mutex
+func (stream *FileStream) open() (err error) {
+	const flags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	const perms = 0644
+	err = os.MkdirAll(path.Dir(stream.Path), os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if stream.file, err = os.OpenFile(stream.Path, flags, perms); err != nil {
+		stream.file = nil
+		return errors.WithStack(err)
+	}
+	now := time.Now()
+	stream.size = 0
+	if info, err := stream.file.Stat(); err == nil && info.Size() > 0 {
+		stream.size = info.Size()
+		now = info.ModTime() // so a file left from a previous period gets rotated on the first write
+	}
+	if stream.RotationInterval > 0 {
+		stream.nextRotation = now.Truncate(stream.RotationInterval).Add(stream.RotationInterval)
+	}
+	if stream.output != nil {
+		stream.output.Reset(stream.file)
+	} else if stream.writer != nil {
+		stream.writer = stream.file
+	}
+	return nil
+}
+
+// shouldRotate tells if the file should be rotated before writing the given amount of bytes
+//
+// The caller must hold the mutex
+func (stream *FileStream) shouldRotate(length int64) bool {
+	if stream.size == 0 {
+		return false // never rotate an empty file
+	}
+	if time.Now().Before(stream.retryRotation) {
+		return false // the last rotation failed
+	}
+	if stream.MaxSize > 0 && stream.size+length > stream.MaxSize {
+		return true
+	}
+	return stream.RotationInterval > 0 && !time.Now().Before(stream.nextRotation)
+}
+
+// rotate flushes and closes the current file, renames it with a timestamp and opens a new one
+//
+// The caller must hold the mutex
+func (stream *FileStream) rotate() (err error) {
+	if stream.output != nil {
+		if err = stream.output.Flush(); err != nil {
+			return errors.WithStack(err) // the buffered data is kept, we will try again at the next write
+		}
+	}
+	if err = stream.file.Close(); err != nil {
+		// The handle cannot be used anymore, the file is opened again by the caller
+		stream.file = nil
+		return errors.WithStack(err)
+	}
+	backup := stream.backupPath(time.Now().UTC())
+	renameErr := os.Rename(stream.Path, backup)
+	if err = stream.open(); err != nil { // If the rename failed, we keep appending to the same file
+		return err
+	}
+	if renameErr != nil {
+		return errors.WithStack(renameErr)
+	}
+	if _, ok := compressionExtensions[stream.Compression]; ok {
+		// Compression happens in the background, the backups get purged when it is done
+		stream.compressions.Add(1)
+		go func(compression string, backup string) {
+			defer stream.compressions.Done()
+			stream.compressionMutex.Lock() // one compression at a time, so purges do not race with them
+			defer stream.compressionMutex.Unlock()
+			if err := compressFile(compression, backup); err != nil {
+				reportError(errors.RuntimeError.Wrap(err))
+			}
+			stream.purgeBackups()
+		}(stream.Compression, backup)
+		return nil
+	}
+	stream.purgeBackups()
+	return nil
+}
+
+// compressFile compresses the given file and deletes it
+//
+// On failure, the original file is kept
+func compressFile(compression, filename string) (err error) {
+	source, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil // the file was purged before we could compress it
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(filename+compressionExtensions[compression], os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = target.Close()
+		if err != nil {
+			_ = os.Remove(target.Name())
+		}
+	}()
+
+	var compressor io.WriteCloser
+	switch compression {
+	case "zstd":
+		if compressor, err = zstd.NewWriter(target); err != nil {
+			return errors.WithStack(err)
+		}
+	default:
+		compressor = gzip.NewWriter(target)
+	}
+	if _, err = io.Copy(compressor, source); err != nil {
+		_ = compressor.Close()
+		return errors.WithStack(err)
+	}
+	if err = compressor.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = target.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	_ = source.Close()
+	return errors.WithStack(os.Remove(filename))
+}
+
+// backupPath computes a unique path for a rotated file
+func (stream *FileStream) backupPath(stamp time.Time) string {
+	folder, prefix, extension := stream.backupComponents()
+	for {
+		backup := filepath.Join(folder, prefix+"-"+stamp.Format(backupTimeFormat)+extension)
+		if _, err := os.Stat(backup); err != nil {
+			return backup // if the path is not usable, renaming will tell
+		}
+		stamp = stamp.Add(time.Millisecond)
+	}
+}
+
+// backupComponents gets the folder, the prefix and the extension of the rotated files
+func (stream *FileStream) backupComponents() (folder, prefix, extension string) {
+	folder, filename := filepath.Split(stream.Path)
+	extension = filepath.Ext(filename)
+	return folder, strings.TrimSuffix(filename, extension), extension
+}
+
+// backups gets the rotated files of this stream, the most recent first
+func (stream *FileStream) backups() (backups []fileBackup) {
+	folder, prefix, extension := stream.backupComponents()
+	if len(folder) == 0 {
+		folder = "."
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix+"-") {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix+"-")
+		for _, compressed := range compressionExtensions {
+			stamp = strings.TrimSuffix(stamp, compressed)
+		}
+		if !strings.HasSuffix(stamp, extension) {
+			continue
+		}
+		stamp = strings.TrimSuffix(stamp, extension)
+		if timestamp, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, fileBackup{Path: filepath.Join(folder, name), Timestamp: timestamp})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Timestamp.After(backups[j].Timestamp) })
+	return
+}
+
+// purgeBackups deletes the rotated files that are beyond MaxBackups or older than MaxAge
+func (stream *FileStream) purgeBackups() {
+	if stream.MaxBackups <= 0 && stream.MaxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().UTC().Add(-stream.MaxAge)
+	for index, backup := range stream.backups() {
+		if (stream.MaxBackups > 0 && index >= stream.MaxBackups) || (stream.MaxAge > 0 && backup.Timestamp.Before(cutoff)) {
+			if err := os.Remove(backup.Path); err != nil && !os.IsNotExist(err) {
+				reportError(errors.RuntimeError.Wrap(err))
+			}
+		}
+	}
+}
+
+// fileStreamOptions are the query parameters of a file destination
+var fileStreamOptions = []string{"maxsize", "rotate", "maxbackups", "maxage", "compress"}
+
+// createFileStream creates a FileStream from a destination
+//
+// The destination can contain rotation options as query parameters:
+//
+//	maxsize: the maximum size of the file before it gets rotated (e.g.: 1024, 10KB, 100MB, 1GiB)
+//	rotate: the rotation interval (hourly, daily, or a duration like 30m, PT6H)
+//	maxbackups: the number of rotated files to keep
+//	maxage: the maximum age of the rotated files to keep (e.g.: 7d, 168h, P1W)
+//	compress: the compression of the rotated files (gzip or zstd)
+//
+// Invalid option values are ignored.
+//
+// If what follows the last "?" is not a query made of these options, it is part of the file name.
+//
+// Example: file:///var/log/myapp.log?maxsize=100MB&maxbackups=5
+func createFileStream(destination string) *FileStream {
+	stream := &FileStream{Path: strings.TrimPrefix(destination, "file://")}
+	index := strings.LastIndex(stream.Path, "?")
+	if index < 0 {
+		return stream
+	}
+	options, err := url.ParseQuery(stream.Path[index+1:])
+	if err != nil || len(options) == 0 {
+		return stream
+	}
+	for key := range options {
+		if !slices.Contains(fileStreamOptions, key) {
+			return stream
+		}
+	}
+	stream.Path = stream.Path[:index]
+	if value := options.Get("maxsize"); len(value) > 0 {
+		if size, err := parseSize(value); err == nil {
+			stream.MaxSize = size
+		}
+	}
+	if value := options.Get("rotate"); len(value) > 0 {
+		if interval, err := parseRotationInterval(value); err == nil {
+			stream.RotationInterval = interval
+		}
+	}
+	if value := options.Get("maxbackups"); len(value) > 0 {
+		stream.MaxBackups = core.Atoi(value, 0)
+	}
+	if value := options.Get("maxage"); len(value) > 0 {
+		if age, err := parseRotationInterval(value); err == nil {
+			stream.MaxAge = age
+		}
+	}
+	if value := strings.ToLower(options.Get("compress")); len(value) > 0 {
+		switch value {
+		case "gzip", "gz":
+			stream.Compression = "gzip"
+		case "zstd", "zst":
+			stream.Compression = "zstd"
+		}
+	}
+	return stream
+}
+
+// parseSize parses a size in bytes with an optional unit (KB, MB, GB, KiB, MiB, GiB)
+func parseSize(value string) (int64, error) {
+	units := []struct {
+		Suffix     string
+		Multiplier int64
+	}{
+		{"KIB", 1024}, {"MIB", 1024 * 1024}, {"GIB", 1024 * 1024 * 1024},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+		{"K", 1024}, {"M", 1024 * 1024}, {"G", 1024 * 1024 * 1024},
+		{"B", 1},
+	}
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.Suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.Suffix))
+			multiplier = unit.Multiplier
+			break
+		}
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, errors.ArgumentInvalid.With("size", value)
+	}
+	return size * multiplier, nil
+}
+
+// parseRotationInterval parses an interval like "hourly", "daily", "7d" or any duration core.ParseDuration understands
+func parseRotationInterval(value string) (time.Duration, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if count, err := strconv.Atoi(days); err == nil && count >= 0 {
+			return time.Duration(count) * 24 * time.Hour, nil
+		}
+	}
+	parse := time.ParseDuration
+	if strings.HasPrefix(value, "p") { // ISO8601 duration
+		parse = core.ParseDuration
+		value = strings.ToUpper(value)
+	}
+	interval, err := parse(value)
+	if err != nil || interval < 0 {
+		return 0, errors.ArgumentInvalid.With("interval", value)
+	}
+	return interval, nil
+}
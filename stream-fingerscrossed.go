@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// FingersCrossedStream is the Stream that buffers the records another Stream would filter out, per request
+//
+// Records are grouped by the value of their Key (default: "reqid", as set by HttpHandler).
+//
+// Records the Destination would filter out (e.g. TRACE, DEBUG) are kept in memory (at most MaxRecords per Key, default: 1000)
+// instead of being discarded, if BufferLevels allows them (default: all levels, but for the topics/scopes the Destination sets to NEVER).
+// As soon as a Record at ActivationLevel (default: ERROR) or above is written for a Key, the buffered records of that Key are written to the Destination, followed by that Record.
+// From then on, all the records of that Key are written to the Destination.
+//
+// The buffered records of a Key are discarded when a Record matching ReleaseWhen is written
+// (default: a Record with the "http_status" key, as written by HttpHandler when a request is finished),
+// when Discard is called, or when no Record was written for that Key for Timeout (default: 1 minute).
+//
+// Records without a Key are written to the Destination if it does not filter them out.
+type FingersCrossedStream struct {
+	Destination     Streamer
+	Key             string
+	ActivationLevel Level
+	BufferLevels    LevelSet
+	MaxRecords      int
+	Timeout         time.Duration
+	ReleaseWhen     func(record *Record) bool
+	buffers         map[string]*fingersCrossedBuffer
+	nextPurge       time.Time
+	mutex           sync.Mutex
+}
+
+type fingersCrossedBuffer struct {
+	Records   []*Record
+	Activated bool
+	LastSeen  time.Time
+}
+
+// GetFilterLevels gets the filter levels of the Destination
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) GetFilterLevels() LevelSet {
+	if stream.Destination == nil {
+		return LevelSet{}
+	}
+	return stream.Destination.GetFilterLevels()
+}
+
+// SetFilterLevel sets the filter level of the Destination
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *FingersCrossedStream) SetFilterLevel(level Level, parameters ...string) {
+	if setter, ok := stream.Destination.(FilterSetter); ok {
+		setter.SetFilterLevel(level, parameters...)
+	}
+}
+
+// FilterMore tells the Destination to filter more
+//
+// implements logger.FilterModifier
+func (stream *FingersCrossedStream) FilterMore() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterMore()
+	}
+}
+
+// FilterLess tells the Destination to filter less
+//
+// implements logger.FilterModifier
+func (stream *FingersCrossedStream) FilterLess() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterLess()
+	}
+}
+
+// Write writes the given Record to the Destination or buffers it
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) Write(record *Record) (err error) {
+	if stream.Destination == nil {
+		return errors.ArgumentMissing.With("Destination")
+	}
+	level := GetLevelFromRecord(record)
+	topic, _ := record.Get("topic").(string)
+	scope, _ := record.Get("scope").(string)
+
+	stream.mutex.Lock()
+	if stream.buffers == nil {
+		if len(stream.Key) == 0 {
+			stream.Key = "reqid"
+		}
+		if stream.ActivationLevel == UNSET {
+			stream.ActivationLevel = ERROR
+		}
+		if stream.MaxRecords <= 0 {
+			stream.MaxRecords = 1000
+		}
+		if stream.Timeout <= 0 {
+			stream.Timeout = time.Minute
+		}
+		if stream.ReleaseWhen == nil {
+			stream.ReleaseWhen = func(record *Record) bool {
+				_, found := record.Find("http_status")
+				return found
+			}
+		}
+		stream.buffers = map[string]*fingersCrossedBuffer{}
+	}
+	now := time.Now()
+	stream.purge(now)
+
+	key := stringValue(record.Get(stream.Key), nil)
+	if len(key) == 0 {
+		stream.mutex.Unlock()
+		if stream.Destination.ShouldWrite(level, topic, scope) {
+			return stream.Destination.Write(record)
+		}
+		return nil
+	}
+
+	buffer, found := stream.buffers[key]
+	if !found {
+		buffer = &fingersCrossedBuffer{}
+		stream.buffers[key] = buffer
+	}
+	buffer.LastSeen = now
+	var records []*Record
+	if level >= stream.ActivationLevel {
+		records = buffer.Records
+		buffer.Records = nil
+		buffer.Activated = true
+	}
+	if buffer.Activated || stream.Destination.ShouldWrite(level, topic, scope) {
+		records = append(records, record)
+	} else if stream.shouldBuffer(level, topic, scope) {
+		if len(buffer.Records) >= stream.MaxRecords {
+			buffer.Records = buffer.Records[1:]
+		}
+		buffer.Records = append(buffer.Records, record.Clone())
+	}
+	if stream.ReleaseWhen(record) {
+		delete(stream.buffers, key)
+	}
+	stream.mutex.Unlock()
+
+	var errs errors.MultiError
+	for _, record := range records {
+		errs.Append(stream.Destination.Write(record))
+	}
+	return errs.AsError()
+}
+
+// ShouldLogSourceInfo tells if the Destination logs the source info
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) ShouldLogSourceInfo() bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldLogSourceInfo()
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// The levels the Destination writes, the levels to buffer, and the levels that activate the buffers are accepted.
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) ShouldWrite(level Level, topic, scope string) bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldWrite(level, topic, scope) || stream.shouldBuffer(level, topic, scope) || stream.shouldActivate(level)
+}
+
+// shouldBuffer tells if a record the Destination filters out should be buffered
+func (stream *FingersCrossedStream) shouldBuffer(level Level, topic, scope string) bool {
+	if len(stream.BufferLevels) > 0 {
+		return stream.BufferLevels.ShouldWrite(level, topic, scope)
+	}
+	return level.ShouldWrite(TRACE) && stream.GetFilterLevels().Get(topic, scope) != NEVER
+}
+
+// shouldActivate tells if a record at the given level activates the buffer of its Key
+func (stream *FingersCrossedStream) shouldActivate(level Level) bool {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.ActivationLevel == UNSET {
+		return level >= ERROR
+	}
+	return level >= stream.ActivationLevel
+}
+
+// Flush flushes the Destination
+//
+// The buffered records are not written.
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) Flush() {
+	stream.Destination.Flush()
+}
+
+// Close discards the buffered records and closes the Destination
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) Close() {
+	stream.mutex.Lock()
+	stream.buffers = nil
+	stream.mutex.Unlock()
+	stream.Destination.Close()
+}
+
+// Reopen reopens the Destination if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (stream *FingersCrossedStream) Reopen() error {
+	if reopener, ok := stream.Destination.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// Discard discards the buffered records of the given Key value
+func (stream *FingersCrossedStream) Discard(key string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	delete(stream.buffers, key)
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The Destination is cloned as well.
+//
+// implements logger.Streamer
+func (stream *FingersCrossedStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &FingersCrossedStream{
+		Destination:     stream.Destination.Clone(),
+		Key:             stream.Key,
+		ActivationLevel: stream.ActivationLevel,
+		BufferLevels:    stream.BufferLevels.Clone(),
+		MaxRecords:      stream.MaxRecords,
+		Timeout:         stream.Timeout,
+		ReleaseWhen:     stream.ReleaseWhen,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *FingersCrossedStream) String() string {
+	return fmt.Sprintf("Fingers Crossed Stream (key: %s, activation: %s) to %s", stream.Key, stream.ActivationLevel, stream.Destination)
+}
+
+// purge discards the buffers that were not used for Timeout
+//
+// the caller must hold the mutex
+func (stream *FingersCrossedStream) purge(now time.Time) {
+	if now.Before(stream.nextPurge) {
+		return
+	}
+	for key, buffer := range stream.buffers {
+		if now.Sub(buffer.LastSeen) >= stream.Timeout {
+			delete(stream.buffers, key)
+		}
+	}
+	stream.nextPurge = now.Add(stream.Timeout)
+}
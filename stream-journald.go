@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gildas/go-errors"
+)
+
+// JournaldStream is the Stream that writes to systemd-journald using its native protocol
+//
+// The Record "msg" is sent as MESSAGE, the Level as PRIORITY, the "name" as SYSLOG_IDENTIFIER,
+// and all the other Record keys as uppercase journal fields.
+//
+// When SourceInfo is true, the source information is sent as CODE_FILE, CODE_LINE, and CODE_FUNC.
+//
+// If SocketPath is empty, /run/systemd/journal/socket is used.
+type JournaldStream struct {
+	SocketPath        string
+	FilterLevels      LevelSet
+	SourceInfo        bool
+	connection        *net.UnixConn
+	environmentPrefix EnvironmentPrefix
+	mutex             sync.Mutex
+}
+
+// journaldSocketPath is the default path of the journald socket
+const journaldSocketPath = "/run/systemd/journal/socket"
+
+// journaldFieldNames contains the journal field names of the Record keys that have a special meaning
+var journaldFieldNames = map[string]string{
+	"msg":  "MESSAGE",
+	"name": "SYSLOG_IDENTIFIER",
+	"file": "CODE_FILE",
+	"line": "CODE_LINE",
+	"func": "CODE_FUNC",
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *JournaldStream) GetFilterLevels() LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *JournaldStream) SetFilterLevel(level Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// The stream will filter more if it is not already at the highest level.
+// Which means less log messages will be written to the stream
+//
+// Example: if the stream is at DEBUG, it will be filtering at INFO
+//
+// implements logger.FilterModifier
+func (stream *JournaldStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// The stream will filter less if it is not already at the lowest level.
+// Which means more log messages will be written to the stream
+//
+// Example: if the stream is at INFO, it will be filtering at DEBUG
+//
+// implements logger.FilterModifier
+func (stream *JournaldStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *JournaldStream) Write(record *Record) (err error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(stream.FilterLevels) == 0 {
+		stream.FilterLevels = ParseLevelsFromEnvironmentWithPrefix(stream.environmentPrefix)
+	}
+	if stream.connection == nil {
+		socketPath := stream.SocketPath
+		if len(socketPath) == 0 {
+			socketPath = journaldSocketPath
+		}
+		if stream.connection, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"}); err != nil {
+			stream.connection = nil
+			return errors.WithStack(err)
+		}
+	}
+	payload := stream.format(record)
+	if _, err = stream.connection.Write(payload); err != nil {
+		if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+			// The payload is too big for a datagram, journald accepts it via a file descriptor
+			return sendJournalPayloadWithFileDescriptor(stream.connection, payload)
+		}
+	}
+	return errors.WithStack(err) // If err is nil, WithStack return nil
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *JournaldStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *JournaldStream) ShouldWrite(level Level, topic, scope string) bool {
+	return level.ShouldWrite(stream.FilterLevels.Get(topic, scope))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *JournaldStream) Flush() {
+}
+
+// Close closes the stream
+//
+// implements logger.Streamer
+func (stream *JournaldStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.connection != nil {
+		_ = stream.connection.Close()
+		stream.connection = nil
+	}
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// implements logger.Streamer
+func (stream *JournaldStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &JournaldStream{
+		SocketPath:        stream.SocketPath,
+		FilterLevels:      stream.FilterLevels.Clone(),
+		SourceInfo:        stream.SourceInfo,
+		environmentPrefix: stream.environmentPrefix,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *JournaldStream) String() string {
+	socketPath := stream.SocketPath
+	if len(socketPath) == 0 {
+		socketPath = journaldSocketPath
+	}
+	if len(stream.FilterLevels) > 0 {
+		return fmt.Sprintf("Stream to journald at %s, Filter: %s", socketPath, stream.FilterLevels)
+	}
+	return fmt.Sprintf("Stream to journald at %s", socketPath)
+}
+
+// format formats the Record with the journald native protocol
+//
+// See: https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+func (stream *JournaldStream) format(record *Record) []byte {
+	buffer := bufferPool.Get()
+	defer bufferPool.Put(buffer)
+
+	writeJournalField(buffer, "PRIORITY", strconv.Itoa(syslogSeverity(GetLevelFromRecord(record))))
+	keys := make([]string, 0, len(record.Data))
+	for key := range record.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "level" {
+			continue
+		}
+		if value := stringValue(record.Get(key), record.KeysToRedact); len(value) > 0 {
+			writeJournalField(buffer, journaldFieldName(key), value)
+		}
+	}
+	return append([]byte(nil), buffer.Bytes()...)
+}
+
+// writeJournalField writes a field with the journald native protocol
+//
+// Values containing a newline are written with their length as a 64-bit little endian integer
+func writeJournalField(buffer *bytes.Buffer, name, value string) {
+	buffer.WriteString(name)
+	if strings.Contains(value, "\n") {
+		buffer.WriteByte('\n')
+		_ = binary.Write(buffer, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buffer.WriteByte('=')
+	}
+	buffer.WriteString(value)
+	buffer.WriteByte('\n')
+}
+
+// journaldFieldName converts a Record key into a journal field name
+//
+// Journal field names contain only uppercase letters, digits and underscores,
+// they cannot start with a digit or an underscore and are at most 64 characters long.
+func journaldFieldName(key string) string {
+	if name, found := journaldFieldNames[key]; found {
+		return name
+	}
+	name := strings.Map(func(char rune) rune {
+		switch {
+		case char >= 'a' && char <= 'z':
+			return char - 'a' + 'A'
+		case (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9'):
+			return char
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "X_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
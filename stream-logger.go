@@ -88,6 +88,16 @@ func (log *Logger) Close() {
 	log.stream.Close()
 }
 
+// Reopen reopens the logger's stream if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (log *Logger) Reopen() error {
+	if reopener, ok := log.stream.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
 // Clone clones the logger, so that the new logger is independent of the original one
 //
 // implements logger.Streamer
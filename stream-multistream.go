@@ -129,6 +129,22 @@ func (stream *MultiStream) Close() {
 	}
 }
 
+// Reopen reopens all streams that implement logger.Reopener
+//
+// implements logger.Reopener
+func (stream *MultiStream) Reopen() error {
+	var errs errors.MultiError
+
+	for _, s := range stream.streams {
+		if reopener, ok := s.(Reopener); ok {
+			if err := reopener.Reopen(); err != nil {
+				errs.Append(err)
+			}
+		}
+	}
+	return errs.AsError()
+}
+
 // Clone clones the stream, so that the new stream is independent of the original one
 //
 // implements logger.Streamer
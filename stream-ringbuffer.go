@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// RingBufferStream is the Stream that keeps the last records in memory
+//
+// At most MaxRecords records (default: 1000, unless MaxBytes is set) are kept
+// and, if MaxBytes is set, at most MaxBytes bytes of JSON (the last Record is always kept).
+// When the buffer is full, the oldest records are discarded.
+//
+// If FilterLevels is empty, all records are kept.
+//
+// The stream is also an http.Handler that serves the kept records as NDJSON (one JSON Record per line).
+// The records can be filtered with the query parameters:
+//   - level: the minimum level of the records (e.g.: ?level=warn),
+//   - topic, scope: the topic and scope of the records,
+//   - since, until: the time range of the records, as RFC 3339 times or durations before now (e.g.: ?since=15m),
+//   - limit: the maximum number of records, the most recent ones are served.
+type RingBufferStream struct {
+	Converter    Converter
+	FilterLevels LevelSet
+	MaxRecords   int
+	MaxBytes     int64
+	SourceInfo   bool
+	entries      []ringBufferEntry
+	size         int64
+	mutex        sync.RWMutex
+}
+
+type ringBufferEntry struct {
+	Time    time.Time
+	Level   Level
+	Topic   string
+	Scope   string
+	Payload []byte
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) GetFilterLevels() LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *RingBufferStream) SetFilterLevel(level Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// The stream will filter more if it is not already at the highest level.
+// Which means less log messages will be written to the stream
+//
+// Example: if the stream is at DEBUG, it will be filtering at INFO
+//
+// implements logger.FilterModifier
+func (stream *RingBufferStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// The stream will filter less if it is not already at the lowest level.
+// Which means more log messages will be written to the stream
+//
+// Example: if the stream is at INFO, it will be filtering at DEBUG
+//
+// implements logger.FilterModifier
+func (stream *RingBufferStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) Write(record *Record) error {
+	entry := ringBufferEntry{Level: GetLevelFromRecord(record)}
+	entry.Topic, _ = record.Get("topic").(string)
+	entry.Scope, _ = record.Get("scope").(string)
+	if entry.Time, _ = record.Get("time").(time.Time); entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.Converter == nil {
+		stream.Converter = GetConverterFromEnvironment()
+	}
+	if stream.MaxRecords <= 0 && stream.MaxBytes <= 0 {
+		stream.MaxRecords = 1000
+	}
+	payload, err := stream.Converter.Convert(record).MarshalJSON()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// payload might share its memory with a pooled buffer
+	entry.Payload = append([]byte(nil), payload...)
+
+	stream.entries = append(stream.entries, entry)
+	stream.size += int64(len(entry.Payload))
+	drop := 0
+	for drop < len(stream.entries)-1 && ((stream.MaxRecords > 0 && len(stream.entries)-drop > stream.MaxRecords) || (stream.MaxBytes > 0 && stream.size > stream.MaxBytes)) {
+		stream.size -= int64(len(stream.entries[drop].Payload))
+		drop++
+	}
+	if drop > 0 {
+		clear(stream.entries[:drop]) // let the garbage collector reclaim the payloads
+		stream.entries = stream.entries[drop:]
+	}
+	return nil
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) ShouldWrite(level Level, topic, scope string) bool {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return level.ShouldWrite(stream.FilterLevels.Get(topic, scope))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) Flush() {
+}
+
+// Close closes the stream
+//
+// The kept records are discarded.
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.entries = nil
+	stream.size = 0
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The kept records are not cloned.
+//
+// implements logger.Streamer
+func (stream *RingBufferStream) Clone() Streamer {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return &RingBufferStream{
+		Converter:    stream.Converter,
+		FilterLevels: stream.FilterLevels.Clone(),
+		MaxRecords:   stream.MaxRecords,
+		MaxBytes:     stream.MaxBytes,
+		SourceInfo:   stream.SourceInfo,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *RingBufferStream) String() string {
+	if len(stream.FilterLevels) > 0 {
+		return fmt.Sprintf("Stream to ring buffer, Filter: %s", stream.FilterLevels)
+	}
+	return "Stream to ring buffer"
+}
+
+// Len tells how many records are kept
+func (stream *RingBufferStream) Len() int {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return len(stream.entries)
+}
+
+// ServeHTTP serves the kept records as NDJSON
+//
+// implements http.Handler
+func (stream *RingBufferStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, err := parseRingBufferFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream.mutex.RLock()
+	payloads := make([][]byte, 0, len(stream.entries))
+	for _, entry := range stream.entries {
+		if filter.match(entry) {
+			payloads = append(payloads, entry.Payload)
+		}
+	}
+	stream.mutex.RUnlock()
+
+	if filter.Limit > 0 && len(payloads) > filter.Limit {
+		payloads = payloads[len(payloads)-filter.Limit:]
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	for _, payload := range payloads {
+		_, _ = w.Write(payload)
+		_, _ = w.Write([]byte("\n"))
+	}
+}
+
+type ringBufferFilter struct {
+	Level Level
+	Topic string
+	Scope string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+func (filter ringBufferFilter) match(entry ringBufferEntry) bool {
+	return entry.Level >= filter.Level &&
+		(len(filter.Topic) == 0 || entry.Topic == filter.Topic) &&
+		(len(filter.Scope) == 0 || entry.Scope == filter.Scope) &&
+		(filter.Since.IsZero() || !entry.Time.Before(filter.Since)) &&
+		(filter.Until.IsZero() || !entry.Time.After(filter.Until))
+}
+
+// parseRingBufferFilter parses the filter from the query parameters of the request
+func parseRingBufferFilter(r *http.Request) (filter ringBufferFilter, err error) {
+	query := r.URL.Query()
+	if value := query.Get("level"); len(value) > 0 {
+		var valid bool
+		if filter.Level, valid = validDebugLevel(value); !valid {
+			return filter, errors.ArgumentInvalid.With("level", value)
+		}
+	}
+	filter.Topic = query.Get("topic")
+	filter.Scope = query.Get("scope")
+	if filter.Since, err = parseRingBufferTime("since", query.Get("since")); err != nil {
+		return
+	}
+	if filter.Until, err = parseRingBufferTime("until", query.Get("until")); err != nil {
+		return
+	}
+	if value := query.Get("limit"); len(value) > 0 {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return filter, errors.ArgumentInvalid.With("limit", value)
+		}
+	}
+	return filter, nil
+}
+
+// parseRingBufferTime parses an RFC 3339 time or a duration before now
+func parseRingBufferTime(name, value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	if stamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return stamp, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Time{}, errors.ArgumentInvalid.With(name, value)
+}
@@ -0,0 +1,289 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// SamplingStream is the Stream that caps the number of similar records written to another Stream
+//
+// Records are similar when they have the same level, topic, scope, and message template
+// (the format given to Infof, Debugf, etc).
+//
+// During every Tick (default: 1 second), the First (default: 100) similar records are written,
+// then only every Thereafter (default: 100) record. If Thereafter is negative, no more records are written.
+//
+// Records at ERROR level and above are always written.
+//
+// At the end of each Tick, a WARN summary record with the number of suppressed records (key: "suppressed") is written
+// for every message that was sampled.
+type SamplingStream struct {
+	Destination Streamer
+	First       int
+	Thereafter  int
+	Tick        time.Duration
+	counters    map[samplingKey]*samplingCounter
+	tickEnd     time.Time
+	timer       *time.Timer
+	mutex       sync.Mutex
+}
+
+type samplingKey struct {
+	Level    Level
+	Topic    string
+	Scope    string
+	Template string
+}
+
+type samplingCounter struct {
+	Count      int
+	Suppressed int
+	Sample     *Record
+}
+
+// GetFilterLevels gets the filter levels of the Destination
+//
+// implements logger.Streamer
+func (stream *SamplingStream) GetFilterLevels() LevelSet {
+	if stream.Destination == nil {
+		return LevelSet{}
+	}
+	return stream.Destination.GetFilterLevels()
+}
+
+// SetFilterLevel sets the filter level of the Destination
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *SamplingStream) SetFilterLevel(level Level, parameters ...string) {
+	if setter, ok := stream.Destination.(FilterSetter); ok {
+		setter.SetFilterLevel(level, parameters...)
+	}
+}
+
+// FilterMore tells the Destination to filter more
+//
+// implements logger.FilterModifier
+func (stream *SamplingStream) FilterMore() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterMore()
+	}
+}
+
+// FilterLess tells the Destination to filter less
+//
+// implements logger.FilterModifier
+func (stream *SamplingStream) FilterLess() {
+	if modifier, ok := stream.Destination.(FilterModifier); ok {
+		modifier.FilterLess()
+	}
+}
+
+// Write writes the given Record if it is sampled
+//
+// implements logger.Streamer
+func (stream *SamplingStream) Write(record *Record) error {
+	if stream.Destination == nil {
+		return errors.ArgumentMissing.With("Destination")
+	}
+	level := GetLevelFromRecord(record)
+	if level >= ERROR {
+		return stream.Destination.Write(record)
+	}
+
+	stream.mutex.Lock()
+	if stream.counters == nil {
+		if stream.First <= 0 {
+			stream.First = 100
+		}
+		if stream.Thereafter == 0 {
+			stream.Thereafter = 100
+		}
+		if stream.Tick <= 0 {
+			stream.Tick = time.Second
+		}
+		stream.counters = map[samplingKey]*samplingCounter{}
+	}
+	summaries := stream.rollover(time.Now(), false)
+	topic, _ := record.Get("topic").(string)
+	scope, _ := record.Get("scope").(string)
+	key := samplingKey{Level: level, Topic: topic, Scope: scope, Template: record.messageTemplate()}
+	counter, found := stream.counters[key]
+	if !found {
+		counter = &samplingCounter{}
+		stream.counters[key] = counter
+	}
+	counter.Count++
+	sampled := counter.Count <= stream.First || (stream.Thereafter > 0 && (counter.Count-stream.First)%stream.Thereafter == 0)
+	if !sampled {
+		counter.Suppressed++
+		if counter.Sample == nil {
+			counter.Sample = record.Clone()
+		}
+		if stream.timer == nil {
+			stream.timer = time.AfterFunc(time.Until(stream.tickEnd), stream.summarize)
+		}
+	}
+	stream.mutex.Unlock()
+
+	var errs errors.MultiError
+	errs.Append(stream.write(summaries)...)
+	if sampled {
+		errs.Append(stream.Destination.Write(record))
+	}
+	return errs.AsError()
+}
+
+// ShouldLogSourceInfo tells if the Destination logs the source info
+//
+// implements logger.Streamer
+func (stream *SamplingStream) ShouldLogSourceInfo() bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldLogSourceInfo()
+}
+
+// ShouldWrite tells if the given level should be written to the Destination
+//
+// implements logger.Streamer
+func (stream *SamplingStream) ShouldWrite(level Level, topic, scope string) bool {
+	if stream.Destination == nil {
+		return false
+	}
+	return stream.Destination.ShouldWrite(level, topic, scope)
+}
+
+// Flush writes the pending summary records and flushes the Destination
+//
+// implements logger.Streamer
+func (stream *SamplingStream) Flush() {
+	stream.mutex.Lock()
+	summaries := stream.summaries()
+	stream.mutex.Unlock()
+	for _, err := range stream.write(summaries) {
+		reportError(errors.RuntimeError.Wrap(err))
+	}
+	stream.Destination.Flush()
+}
+
+// Close writes the pending summary records and closes the Destination
+//
+// implements logger.Streamer
+func (stream *SamplingStream) Close() {
+	stream.mutex.Lock()
+	if stream.timer != nil {
+		stream.timer.Stop()
+		stream.timer = nil
+	}
+	summaries := stream.summaries()
+	stream.counters = nil
+	stream.mutex.Unlock()
+	for _, err := range stream.write(summaries) {
+		reportError(errors.RuntimeError.Wrap(err))
+	}
+	stream.Destination.Close()
+}
+
+// Reopen reopens the Destination if it implements logger.Reopener
+//
+// implements logger.Reopener
+func (stream *SamplingStream) Reopen() error {
+	if reopener, ok := stream.Destination.(Reopener); ok {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The Destination is cloned as well.
+//
+// implements logger.Streamer
+func (stream *SamplingStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &SamplingStream{
+		Destination: stream.Destination.Clone(),
+		First:       stream.First,
+		Thereafter:  stream.Thereafter,
+		Tick:        stream.Tick,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *SamplingStream) String() string {
+	return fmt.Sprintf("Sampling Stream (first: %d, thereafter: %d, tick: %s) to %s", stream.First, stream.Thereafter, stream.Tick, stream.Destination)
+}
+
+// rollover starts a new tick if the current one is over, or if forced
+//
+// It returns the summary records of the previous tick.
+//
+// the caller must hold the mutex
+func (stream *SamplingStream) rollover(now time.Time, force bool) (summaries []*Record) {
+	if !force && now.Before(stream.tickEnd) {
+		return nil
+	}
+	summaries = stream.summaries()
+	clear(stream.counters)
+	stream.tickEnd = now.Add(stream.Tick)
+	if stream.timer != nil {
+		stream.timer.Stop()
+		stream.timer = nil
+	}
+	return summaries
+}
+
+// summaries gets the summary records of the suppressed records and resets their count
+//
+// the caller must hold the mutex
+func (stream *SamplingStream) summaries() (summaries []*Record) {
+	for key, counter := range stream.counters {
+		if counter.Suppressed == 0 {
+			continue
+		}
+		summary := counter.Sample
+		summary.template = ""
+		summary.Data["time"] = time.Now().UTC()
+		summary.Data["level"] = WARN
+		summary.Data["msg"] = fmt.Sprintf("Suppressed %d %s records: %s", counter.Suppressed, key.Level, key.Template)
+		summary.Data["suppressed"] = counter.Suppressed
+		summaries = append(summaries, summary)
+		counter.Suppressed = 0
+		counter.Sample = nil
+	}
+	return
+}
+
+// summarize writes the summary records at the end of a tick
+func (stream *SamplingStream) summarize() {
+	stream.mutex.Lock()
+	if stream.counters == nil { // The stream was closed
+		stream.mutex.Unlock()
+		return
+	}
+	summaries := stream.rollover(time.Now(), true)
+	stream.mutex.Unlock()
+	for _, err := range stream.write(summaries) {
+		reportError(errors.RuntimeError.Wrap(err))
+	}
+}
+
+// write writes the given records to the Destination
+func (stream *SamplingStream) write(records []*Record) (errs []error) {
+	for _, record := range records {
+		if err := stream.Destination.Write(record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
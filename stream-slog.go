@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/google/uuid"
+)
+
+// SlogStream is the Stream that writes to a log/slog Handler
+//
+// The level, time, and msg of the records become the level, time, and message of the slog records,
+// the other fields become attributes (sorted by key).
+// The values are redacted and skipped when empty, as they would be in JSON.
+//
+// Levels are mapped to slog levels: TRACE is slog.LevelDebug-4, DEBUG is slog.LevelDebug, INFO is slog.LevelInfo,
+// WARN is slog.LevelWarn, ERROR is slog.LevelError, FATAL is slog.LevelError+4, and ALWAYS is slog.LevelError+8.
+//
+// Records are written only if both the FilterLevels and the Handler allow their level.
+//
+// Example:
+//
+//	var Log = logger.Create("myapp", &logger.SlogStream{Handler: slog.NewTextHandler(os.Stderr, nil)})
+type SlogStream struct {
+	Handler      slog.Handler
+	FilterLevels LevelSet
+	SourceInfo   bool
+	mutex        sync.RWMutex
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *SlogStream) GetFilterLevels() LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *SlogStream) SetFilterLevel(level Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// The stream will filter more if it is not already at the highest level.
+// Which means less log messages will be written to the stream
+//
+// Example: if the stream is at DEBUG, it will be filtering at INFO
+//
+// implements logger.FilterModifier
+func (stream *SlogStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// The stream will filter less if it is not already at the lowest level.
+// Which means more log messages will be written to the stream
+//
+// Example: if the stream is at INFO, it will be filtering at DEBUG
+//
+// implements logger.FilterModifier
+func (stream *SlogStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *SlogStream) Write(record *Record) error {
+	if stream.Handler == nil {
+		return errors.ArgumentMissing.With("Handler")
+	}
+	stamp, ok := record.Get("time").(time.Time)
+	if !ok {
+		stamp = time.Now()
+	}
+	message, _ := record.Get("msg").(string)
+	slogRecord := slog.NewRecord(stamp, levelToSlog(GetLevelFromRecord(record)), message, 0)
+
+	keys := make([]string, 0, len(record.Data))
+	for key := range record.Data {
+		switch key {
+		case "time", "level", "msg":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if attr, ok := slogAttr(key, record.Data[key], record.KeysToRedact); ok {
+			slogRecord.AddAttrs(attr)
+		}
+	}
+	return errors.WithStack(stream.Handler.Handle(context.Background(), slogRecord))
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *SlogStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *SlogStream) ShouldWrite(level Level, topic, scope string) bool {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	if !level.ShouldWrite(stream.FilterLevels.Get(topic, scope)) {
+		return false
+	}
+	return stream.Handler == nil || stream.Handler.Enabled(context.Background(), levelToSlog(level))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *SlogStream) Flush() {
+}
+
+// Close closes the stream
+//
+// implements logger.Streamer
+func (stream *SlogStream) Close() {
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The Handler is shared with the clone.
+//
+// implements logger.Streamer
+func (stream *SlogStream) Clone() Streamer {
+	stream.mutex.RLock()
+	defer stream.mutex.RUnlock()
+	return &SlogStream{
+		Handler:      stream.Handler,
+		FilterLevels: stream.FilterLevels.Clone(),
+		SourceInfo:   stream.SourceInfo,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *SlogStream) String() string {
+	if len(stream.FilterLevels) > 0 {
+		return fmt.Sprintf("Stream to slog handler %T, Filter: %s", stream.Handler, stream.FilterLevels)
+	}
+	return fmt.Sprintf("Stream to slog handler %T", stream.Handler)
+}
+
+// slogAttr converts a Record value into a slog attribute
+//
+// funcs are called and Redactable values are redacted.
+// Empty values are skipped, unless the key starts with "?", like when the Record is marshaled.
+func slogAttr(key string, raw any, keysToRedact []string) (slog.Attr, bool) {
+	showNils := strings.HasPrefix(key, "?")
+	key = strings.TrimPrefix(key, "?")
+	if value, ok := raw.(func() any); ok {
+		raw = value()
+	}
+	switch value := raw.(type) {
+	case RedactableWithKeys:
+		raw = value.Redact(keysToRedact...)
+	case Redactable:
+		raw = value.Redact()
+	}
+	if !showNils {
+		if raw == nil {
+			return slog.Attr{}, false
+		}
+		if value, ok := raw.(string); ok && value == "" {
+			return slog.Attr{}, false
+		}
+		if id, ok := raw.(uuid.UUID); ok && id == uuid.Nil {
+			return slog.Attr{}, false
+		}
+		if id, ok := raw.(interface{ IsNil() bool }); ok && id.IsNil() {
+			return slog.Attr{}, false
+		}
+	}
+	return slog.Any(key, raw), true
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -98,7 +99,7 @@ func (stream *StackDriverStream) Write(record *Record) (err error) {
 			}
 			stream.Parent = "projects/" + projectID
 		}
-		stream.Converter = &StackDriverConverter{}
+		stream.Converter = &StackDriverConverter{ProjectID: strings.TrimPrefix(stream.Parent, "projects/")}
 		options := []googleoption.ClientOption{}
 		if stream.Key != nil {
 			payload, err := json.Marshal(stream.Key)
@@ -124,10 +125,20 @@ func (stream *StackDriverStream) Write(record *Record) (err error) {
 	severity := grecord.Get("severity").(logging.Severity)
 	grecord.Delete("time")
 	grecord.Delete("severity")
+	// The trace fields are only read from the payload by the logging agents, the API needs them in the Entry
+	traceID, _ := grecord.Get("logging.googleapis.com/trace").(string)
+	spanID, _ := grecord.Get("logging.googleapis.com/spanId").(string)
+	sampled, _ := grecord.Get("logging.googleapis.com/trace_sampled").(bool)
+	grecord.Delete("logging.googleapis.com/trace")
+	grecord.Delete("logging.googleapis.com/spanId")
+	grecord.Delete("logging.googleapis.com/trace_sampled")
 	stream.target.Log(logging.Entry{
-		Timestamp: stamp,
-		Severity:  severity,
-		Payload:   grecord,
+		Timestamp:    stamp,
+		Severity:     severity,
+		Payload:      grecord,
+		Trace:        traceID,
+		SpanID:       spanID,
+		TraceSampled: sampled,
 	})
 	return nil
 }
@@ -83,11 +83,11 @@ func (stream *StderrStream) Write(record *Record) (err error) {
 	if len(stream.FilterLevels) == 0 {
 		stream.FilterLevels = ParseLevelsFromEnvironmentWithPrefix(stream.environmentPrefix)
 	}
-	payload, _ := stream.Converter.Convert(record).MarshalJSON()
-	_, err = os.Stderr.Write(payload)
-	if err == nil { // Keep working as long as there is no error
-		_, err = os.Stderr.Write([]byte("\n"))
+	payload, err := JSONEncoder{}.Encode(stream.Converter.Convert(record))
+	if err != nil {
+		return errors.WithStack(err)
 	}
+	_, err = os.Stderr.Write(payload)
 	return errors.WithStack(err) // If err is nil, WithStack return nil
 }
 
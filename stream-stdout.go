@@ -101,15 +101,13 @@ func (stream *StdoutStream) Write(record *Record) (err error) {
 			go stream.flushJob()
 		}
 	}
-	payload, _ := stream.Converter.Convert(record).MarshalJSON()
+	payload, err := JSONEncoder{}.Encode(stream.Converter.Convert(record))
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	_, err = stream.writer.Write(payload)
-	if err == nil { // Keep working as long as there is no error
-		_, err = stream.writer.Write([]byte("\n"))
-		if err == nil { // Keep working as long as there is no error
-			if GetLevelFromRecord(record) >= ERROR && stream.output != nil {
-				_ = stream.output.Flush() // calling stream.Flush would Lock the mutex again and end up with a dead-lock
-			}
-		}
+	if err == nil && GetLevelFromRecord(record) >= ERROR && stream.output != nil {
+		_ = stream.output.Flush() // calling stream.Flush would Lock the mutex again and end up with a dead-lock
 	}
 	return errors.WithStack(err) // If err is nil, WithStack return nil
 }
@@ -0,0 +1,451 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// SyslogStream is the Stream that writes to a syslog server
+//
+// The Address can be a unix socket path (default: /dev/log), "unix:///path/to/socket",
+// "udp://host:port" or "tcp://host:port". Messages sent over TCP use the octet-counting framing (RFC 6587).
+//
+// The Format can be "rfc5424" (default) or "rfc3164".
+// With RFC 5424, the Record fields are sent as structured data.
+//
+// The Facility is a syslog facility name like "user" (default), "daemon", "local0", etc.
+//
+// If AppName is empty, the "name" of the Record is used.
+type SyslogStream struct {
+	Address           string
+	Format            string
+	Facility          string
+	AppName           string
+	StructuredDataID  string
+	FilterLevels      LevelSet
+	SourceInfo        bool
+	connection        net.Conn
+	network           string
+	environmentPrefix EnvironmentPrefix
+	mutex             sync.Mutex
+}
+
+// syslogFacilities contains the syslog facility codes per name
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogHeaderKeys contains the Record keys that are sent in the syslog header rather than in the structured data
+var syslogHeaderKeys = map[string]bool{
+	"time":     true,
+	"level":    true,
+	"msg":      true,
+	"name":     true,
+	"hostname": true,
+	"pid":      true,
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *SyslogStream) GetFilterLevels() LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *SyslogStream) SetFilterLevel(level Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// The stream will filter more if it is not already at the highest level.
+// Which means less log messages will be written to the stream
+//
+// Example: if the stream is at DEBUG, it will be filtering at INFO
+//
+// implements logger.FilterModifier
+func (stream *SyslogStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// The stream will filter less if it is not already at the lowest level.
+// Which means more log messages will be written to the stream
+//
+// Example: if the stream is at INFO, it will be filtering at DEBUG
+//
+// implements logger.FilterModifier
+func (stream *SyslogStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *SyslogStream) Write(record *Record) (err error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(stream.FilterLevels) == 0 {
+		stream.FilterLevels = ParseLevelsFromEnvironmentWithPrefix(stream.environmentPrefix)
+	}
+	if stream.connection == nil {
+		if err = stream.connect(); err != nil {
+			return err
+		}
+	}
+	var payload []byte
+	if strings.ToLower(stream.Format) == "rfc3164" {
+		payload = stream.formatRFC3164(record)
+	} else {
+		payload = stream.formatRFC5424(record)
+	}
+	if stream.network == "tcp" {
+		payload = append([]byte(strconv.Itoa(len(payload))+" "), payload...)
+	} else if stream.network == "unix" {
+		payload = append(payload, '\n')
+	}
+	if _, err = stream.connection.Write(payload); err != nil {
+		// The syslog server might have been restarted, let's try to reconnect once
+		_ = stream.connection.Close()
+		stream.connection = nil
+		if err = stream.connect(); err != nil {
+			return err
+		}
+		_, err = stream.connection.Write(payload)
+	}
+	return errors.WithStack(err) // If err is nil, WithStack return nil
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *SyslogStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *SyslogStream) ShouldWrite(level Level, topic, scope string) bool {
+	return level.ShouldWrite(stream.FilterLevels.Get(topic, scope))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *SyslogStream) Flush() {
+}
+
+// Close closes the stream
+//
+// implements logger.Streamer
+func (stream *SyslogStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.connection != nil {
+		_ = stream.connection.Close()
+		stream.connection = nil
+	}
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// implements logger.Streamer
+func (stream *SyslogStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &SyslogStream{
+		Address:           stream.Address,
+		Format:            stream.Format,
+		Facility:          stream.Facility,
+		AppName:           stream.AppName,
+		StructuredDataID:  stream.StructuredDataID,
+		FilterLevels:      stream.FilterLevels.Clone(),
+		SourceInfo:        stream.SourceInfo,
+		environmentPrefix: stream.environmentPrefix,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *SyslogStream) String() string {
+	address := stream.Address
+	if len(address) == 0 {
+		address = "/dev/log"
+	}
+	if len(stream.FilterLevels) > 0 {
+		return fmt.Sprintf("Stream to syslog at %s, Filter: %s", address, stream.FilterLevels)
+	}
+	return fmt.Sprintf("Stream to syslog at %s", address)
+}
+
+// connect connects to the syslog server
+//
+// The caller must hold the mutex
+func (stream *SyslogStream) connect() (err error) {
+	network, address := parseSyslogAddress(stream.Address)
+	if network == "unix" {
+		// Local syslog daemons usually listen on datagram sockets
+		if stream.connection, err = net.Dial("unixgram", address); err == nil {
+			stream.network = "unixgram"
+			return nil
+		}
+	}
+	if stream.connection, err = net.Dial(network, address); err != nil {
+		stream.connection = nil
+		return errors.WithStack(err)
+	}
+	stream.network = network
+	return nil
+}
+
+// parseSyslogAddress gets the network and the address of a syslog server
+func parseSyslogAddress(address string) (network, location string) {
+	if len(address) == 0 {
+		return "unix", "/dev/log"
+	}
+	if scheme, location, found := strings.Cut(address, "://"); found {
+		switch strings.ToLower(scheme) {
+		case "udp", "tcp":
+			return strings.ToLower(scheme), location
+		default:
+			return "unix", location
+		}
+	}
+	return "unix", address
+}
+
+// priority computes the syslog priority of a Record
+func (stream *SyslogStream) priority(record *Record) int {
+	facility, found := syslogFacilities[strings.ToLower(stream.Facility)]
+	if !found {
+		facility = syslogFacilities["user"]
+	}
+	return facility*8 + syslogSeverity(GetLevelFromRecord(record))
+}
+
+// syslogSeverity converts a Level into a syslog severity
+func syslogSeverity(level Level) int {
+	switch level {
+	case TRACE, DEBUG:
+		return 7 // debug
+	case INFO:
+		return 6 // informational
+	case WARN:
+		return 4 // warning
+	case ERROR:
+		return 3 // error
+	case FATAL:
+		return 2 // critical
+	case ALWAYS:
+		return 5 // notice
+	default:
+		return 6 // informational
+	}
+}
+
+// formatRFC5424 formats the Record as a RFC 5424 syslog message
+func (stream *SyslogStream) formatRFC5424(record *Record) []byte {
+	buffer := bufferPool.Get()
+	defer bufferPool.Put(buffer)
+
+	stamp := "-"
+	if value, ok := record.Get("time").(time.Time); ok {
+		stamp = value.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	fmt.Fprintf(buffer, "<%d>1 %s %s %s %s - ",
+		stream.priority(record),
+		stamp,
+		syslogHeaderField(stream.hostname(record), 255),
+		syslogHeaderField(stream.appName(record), 48),
+		syslogHeaderField(stringValue(record.Get("pid"), nil), 128),
+	)
+
+	keys := make([]string, 0, len(record.Data))
+	for key, value := range record.Data {
+		if !syslogHeaderKeys[key] && value != nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		buffer.WriteString("-")
+	} else {
+		sort.Strings(keys)
+		sdid := stream.StructuredDataID
+		if len(sdid) == 0 {
+			sdid = "logger@32473"
+		}
+		buffer.WriteString("[")
+		buffer.WriteString(sdid)
+		for _, key := range keys {
+			buffer.WriteString(" ")
+			buffer.WriteString(syslogParamName(key))
+			buffer.WriteString(`="`)
+			buffer.WriteString(syslogParamValueReplacer.Replace(stringValue(record.Get(key), record.KeysToRedact)))
+			buffer.WriteString(`"`)
+		}
+		buffer.WriteString("]")
+	}
+	if message := stringValue(record.Get("msg"), nil); len(message) > 0 {
+		buffer.WriteString(" ")
+		buffer.WriteString(message)
+	}
+	return append([]byte(nil), buffer.Bytes()...)
+}
+
+// formatRFC3164 formats the Record as a RFC 3164 (BSD) syslog message
+func (stream *SyslogStream) formatRFC3164(record *Record) []byte {
+	stamp, ok := record.Get("time").(time.Time)
+	if !ok {
+		stamp = time.Now()
+	}
+	tag := syslogHeaderField(stream.appName(record), 32)
+	if pid := stringValue(record.Get("pid"), nil); len(pid) > 0 {
+		tag += "[" + pid + "]"
+	}
+	return fmt.Appendf(nil, "<%d>%s %s %s: %s",
+		stream.priority(record),
+		stamp.Local().Format(time.Stamp),
+		syslogHeaderField(stream.hostname(record), 255),
+		tag,
+		stringValue(record.Get("msg"), nil),
+	)
+}
+
+// hostname gets the hostname to send in the syslog header
+func (stream *SyslogStream) hostname(record *Record) string {
+	if hostname, ok := record.Get("hostname").(string); ok && len(hostname) > 0 {
+		return hostname
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// appName gets the application name to send in the syslog header
+func (stream *SyslogStream) appName(record *Record) string {
+	if len(stream.AppName) > 0 {
+		return stream.AppName
+	}
+	if name, ok := record.Get("name").(string); ok {
+		return name
+	}
+	return ""
+}
+
+// syslogParamValueReplacer escapes the characters RFC 5424 forbids in structured data values
+var syslogParamValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeaderField sanitizes a syslog header field (printable US-ASCII, no space, limited length)
+//
+// If the field is empty, the NILVALUE "-" is returned
+func syslogHeaderField(value string, maxLength int) string {
+	field := strings.Map(func(char rune) rune {
+		if char < 33 || char > 126 {
+			return -1
+		}
+		return char
+	}, value)
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	if len(field) == 0 {
+		return "-"
+	}
+	return field
+}
+
+// syslogParamName sanitizes a structured data parameter name
+func syslogParamName(name string) string {
+	field := strings.Map(func(char rune) rune {
+		if char < 33 || char > 126 || char == '=' || char == ']' || char == '"' {
+			return '_'
+		}
+		return char
+	}, name)
+	if len(field) > 32 {
+		field = field[:32]
+	}
+	return field
+}
+
+// createSyslogStream creates a SyslogStream from a destination
+//
+// The destination can be:
+//
+//	syslog: writes to /dev/log
+//	syslog:///path/to/socket: writes to the given unix socket
+//	syslog://host:port or syslog+udp://host:port: writes to the given host over UDP
+//	syslog+tcp://host:port: writes to the given host over TCP
+//
+// The destination accepts the query parameters format (rfc5424, rfc3164), facility, and app.
+//
+// Example: syslog+tcp://logs.acme.com:6514?format=rfc5424&facility=local0&app=myapp
+func createSyslogStream(destination string) *SyslogStream {
+	stream := &SyslogStream{}
+	location, err := url.Parse(destination)
+	if err != nil {
+		return stream
+	}
+	switch {
+	case len(location.Host) == 0 && len(location.Path) > 0:
+		stream.Address = "unix://" + location.Path
+	case strings.HasSuffix(strings.ToLower(location.Scheme), "+tcp"):
+		stream.Address = "tcp://" + location.Host
+	case len(location.Host) > 0:
+		stream.Address = "udp://" + location.Host
+	}
+	options := location.Query()
+	stream.Format = options.Get("format")
+	stream.Facility = options.Get("facility")
+	stream.AppName = options.Get("app")
+	return stream
+}
@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// WriterStream is the Stream that writes to any io.Writer
+//
+// Records are converted with the Converter, then encoded with the Encoder (JSON, one Record per line, by default).
+//
+// Unless Unbuffered is true, the output is buffered and flushed periodically (see LOG_FLUSHFREQUENCY)
+// and every time a Record with a level of ERROR or more is written.
+//
+// If the Writer has a Flush method (e.g.: gzip.Writer, http.Flusher), it is called when the stream is flushed.
+//
+// The Writer is not closed by Close, its owner is responsible for that.
+type WriterStream struct {
+	Writer            io.Writer
+	Converter         Converter
+	Encoder           Encoder
+	FilterLevels      LevelSet
+	Unbuffered        bool
+	SourceInfo        bool
+	output            *bufio.Writer
+	writer            io.Writer
+	flushFrequency    time.Duration
+	stopFlushJob      chan struct{}
+	environmentPrefix EnvironmentPrefix
+	mutex             sync.Mutex
+}
+
+// GetFilterLevels gets the filter levels
+//
+// implements logger.Streamer
+func (stream *WriterStream) GetFilterLevels() LevelSet {
+	return stream.FilterLevels
+}
+
+// SetFilterLevel sets the filter level
+//
+// If present, the first parameter is the topic.
+//
+// If present, the second parameter is the scope.
+//
+// implements logger.FilterSetter
+func (stream *WriterStream) SetFilterLevel(level Level, parameters ...string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if len(parameters) == 0 {
+		stream.FilterLevels.SetDefault(level)
+	} else if len(parameters) == 1 {
+		stream.FilterLevels.Set(level, parameters[0], "")
+	} else {
+		stream.FilterLevels.Set(level, parameters[0], parameters[1])
+	}
+}
+
+// FilterMore tells the stream to filter more
+//
+// The stream will filter more if it is not already at the highest level.
+// Which means less log messages will be written to the stream
+//
+// Example: if the stream is at DEBUG, it will be filtering at INFO
+//
+// implements logger.FilterModifier
+func (stream *WriterStream) FilterMore() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Next())
+}
+
+// FilterLess tells the stream to filter less
+//
+// The stream will filter less if it is not already at the lowest level.
+// Which means more log messages will be written to the stream
+//
+// Example: if the stream is at INFO, it will be filtering at DEBUG
+//
+// implements logger.FilterModifier
+func (stream *WriterStream) FilterLess() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.FilterLevels.SetDefault(stream.FilterLevels.GetDefault().Previous())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *WriterStream) Write(record *Record) (err error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.Writer == nil {
+		return errors.ArgumentMissing.With("Writer")
+	}
+	if stream.writer == nil {
+		if stream.Converter == nil {
+			stream.Converter = GetConverterFromEnvironmentWithPrefix(stream.environmentPrefix)
+		}
+		if stream.Encoder == nil {
+			stream.Encoder = JSONEncoder{}
+		}
+		if len(stream.FilterLevels) == 0 {
+			stream.FilterLevels = ParseLevelsFromEnvironmentWithPrefix(stream.environmentPrefix)
+		}
+		if stream.Unbuffered {
+			stream.output = nil
+			stream.writer = stream.Writer
+		} else {
+			stream.output = bufio.NewWriter(stream.Writer)
+			stream.writer = stream.output
+			stream.flushFrequency = GetFlushFrequencyFromEnvironmentWithPrefix(stream.environmentPrefix)
+			stream.stopFlushJob = make(chan struct{})
+			go stream.flushJob(stream.stopFlushJob)
+		}
+	}
+	payload, err := stream.Encoder.Encode(stream.Converter.Convert(record))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = stream.writer.Write(payload); err == nil && GetLevelFromRecord(record) >= ERROR {
+		err = stream.flush() // calling stream.Flush would Lock the mutex again and end up with a dead-lock
+	}
+	return errors.WithStack(err) // If err is nil, WithStack return nil
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *WriterStream) ShouldLogSourceInfo() bool {
+	return stream.SourceInfo
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *WriterStream) ShouldWrite(level Level, topic, scope string) bool {
+	return level.ShouldWrite(stream.FilterLevels.Get(topic, scope))
+}
+
+// Flush flushes the stream (makes sure records are actually written)
+//
+// implements logger.Streamer
+func (stream *WriterStream) Flush() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	_ = stream.flush()
+}
+
+// Close closes the stream
+//
+// The stream is flushed, the Writer is not closed.
+//
+// implements logger.Streamer
+func (stream *WriterStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	_ = stream.flush()
+	if stream.stopFlushJob != nil {
+		close(stream.stopFlushJob)
+		stream.stopFlushJob = nil
+	}
+	stream.output = nil
+	stream.writer = nil
+}
+
+// Clone clones the stream, so that the new stream is independent of the original one
+//
+// The clone writes to the same Writer.
+//
+// implements logger.Streamer
+func (stream *WriterStream) Clone() Streamer {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return &WriterStream{
+		Writer:            stream.Writer,
+		Converter:         stream.Converter,
+		Encoder:           stream.Encoder,
+		FilterLevels:      stream.FilterLevels.Clone(),
+		Unbuffered:        stream.Unbuffered,
+		SourceInfo:        stream.SourceInfo,
+		environmentPrefix: stream.environmentPrefix,
+	}
+}
+
+// String gets a string version
+//
+// implements fmt.Stringer
+func (stream *WriterStream) String() string {
+	format := bufferPool.Get()
+	defer bufferPool.Put(format)
+
+	if stream.Unbuffered {
+		_, _ = format.WriteString("Unbuffered ")
+	}
+	_, _ = format.WriteString("Stream to writer %T")
+	if len(stream.FilterLevels) > 0 {
+		_, _ = format.WriteString(", Filter: %s")
+		return fmt.Sprintf(format.String(), stream.Writer, stream.FilterLevels)
+	}
+	return fmt.Sprintf(format.String(), stream.Writer)
+}
+
+// flush flushes the buffer and the Writer if it can be flushed
+//
+// the caller must hold the mutex
+func (stream *WriterStream) flush() (err error) {
+	if stream.output != nil {
+		if err = stream.output.Flush(); err != nil {
+			return err
+		}
+	}
+	if stream.writer != nil {
+		switch flusher := stream.Writer.(type) {
+		case interface{ Flush() error }:
+			return flusher.Flush()
+		case interface{ Flush() }:
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+func (stream *WriterStream) flushJob(stop chan struct{}) {
+	ticker := time.NewTicker(stream.flushFrequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stream.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -32,6 +32,14 @@ type Streamer interface {
 	Clone() Streamer
 }
 
+// Reopener describes streams that can reopen their destination
+//
+// This is typically used after an external tool (like logrotate) moved the files the streams write to
+type Reopener interface {
+	// Reopen flushes the stream, closes its destination and opens it again
+	Reopen() error
+}
+
 // GetFlushFrequencyFromEnvironment fetches the flush frequency from the LOG_FLUSHFREQUENCY environment
 //
 // the frequency should be like https://golang.org/pkg/time/#ParseDuration or an ISO8601 duration.
@@ -61,10 +69,17 @@ func GetFlushFrequencyFromEnvironmentWithPrefix(prefix EnvironmentPrefix) time.D
 //
 // "stackdriver" will create a StackDriverStream
 //
+// "syslog", "syslog:///path/to/socket", "syslog://host:port", "syslog+tcp://host:port" will create a SyslogStream
+//
+// "journald", "journal", "journald:///path/to/socket" will create a JournaldStream
+//
 // "gcp", "googlecloud", "google" will create a StdoutStream, unbuffered, with the StackDriverConverter
 //
 // "file:///path/to/file" or "path/to/file", "/path/to/file" will create a FileStream on the given location
 //
+// File destinations accept rotation options as query parameters (maxsize, rotate, maxbackups, maxage),
+// e.g.: "file:///var/log/myapp.log?maxsize=100MB&rotate=daily&maxbackups=7"
+//
 // If more than one string is given, a MultiStream of all Streams from strings is created.
 //
 // If the environment variable DEBUG is set to 1, all Streams are created unbuffered.
@@ -84,10 +99,17 @@ func CreateStream(levels LevelSet, destinations ...string) Streamer {
 //
 // "stackdriver" will create a StackDriverStream
 //
+// "syslog", "syslog:///path/to/socket", "syslog://host:port", "syslog+tcp://host:port" will create a SyslogStream
+//
+// "journald", "journal", "journald:///path/to/socket" will create a JournaldStream
+//
 // "gcp", "googlecloud", "google" will create a StdoutStream, unbuffered, with the StackDriverConverter
 //
 // "file:///path/to/file" or "path/to/file", "/path/to/file" will create a FileStream on the given location
 //
+// File destinations accept rotation options as query parameters (maxsize, rotate, maxbackups, maxage),
+// e.g.: "file:///var/log/myapp.log?maxsize=100MB&rotate=daily&maxbackups=7"
+//
 // If more than one string is given, a MultiStream of all Streams from strings is created.
 //
 // If the environment variable DEBUG is set to 1, all Streams are created unbuffered.
@@ -117,13 +139,28 @@ func CreateStreamWithPrefix(prefix EnvironmentPrefix, levels LevelSet, destinati
 			stream = &StdoutStream{FilterLevels: levels, Unbuffered: true, SourceInfo: sourceInfo, Converter: &StackDriverConverter{}, environmentPrefix: prefix}
 		case "stackdriver":
 			stream = &StackDriverStream{FilterLevels: levels, SourceInfo: sourceInfo}
+		case "syslog":
+			stream = &SyslogStream{FilterLevels: levels, SourceInfo: sourceInfo, environmentPrefix: prefix}
+		case "journald", "journal":
+			stream = &JournaldStream{FilterLevels: levels, SourceInfo: sourceInfo, environmentPrefix: prefix}
 		case "nil", "null", "void", "blackhole", "nether":
 			stream = &NilStream{}
 		default:
-			if strings.HasPrefix(destination, "file://") {
-				stream = &FileStream{FilterLevels: levels, Path: strings.TrimPrefix(destination, "file://"), Unbuffered: unbuffered, environmentPrefix: prefix, SourceInfo: sourceInfo}
+			if lower := strings.ToLower(destination); strings.HasPrefix(lower, "syslog://") || strings.HasPrefix(lower, "syslog+") {
+				syslogStream := createSyslogStream(destination)
+				syslogStream.FilterLevels = levels
+				syslogStream.SourceInfo = sourceInfo
+				syslogStream.environmentPrefix = prefix
+				stream = syslogStream
+			} else if strings.HasPrefix(lower, "journald://") {
+				stream = &JournaldStream{SocketPath: destination[len("journald://"):], FilterLevels: levels, SourceInfo: sourceInfo, environmentPrefix: prefix}
 			} else if len(destination) > 0 {
-				stream = &FileStream{FilterLevels: levels, Path: destination, Unbuffered: unbuffered, SourceInfo: sourceInfo, environmentPrefix: prefix}
+				fileStream := createFileStream(destination)
+				fileStream.FilterLevels = levels
+				fileStream.Unbuffered = unbuffered
+				fileStream.SourceInfo = sourceInfo
+				fileStream.environmentPrefix = prefix
+				stream = fileStream
 			} else {
 				stream = &StdoutStream{FilterLevels: levels, Unbuffered: unbuffered, SourceInfo: sourceInfo, environmentPrefix: prefix}
 			}
@@ -0,0 +1,213 @@
+package logger_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type AsyncStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestAsyncStreamSuite(t *testing.T) {
+	suite.Run(t, new(AsyncStreamSuite))
+}
+
+func (suite *AsyncStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *AsyncStreamSuite) TestCanWriteAsynchronously() {
+	destination := &RecordingStream{}
+	stream := &logger.AsyncStream{Destination: destination}
+	log := logger.Create("test", stream)
+	for i := 0; i < 100; i++ {
+		log.Infof("message %d", i)
+	}
+	log.Flush()
+	messages := destination.Messages()
+	suite.Require().Len(messages, 100)
+	for i, message := range messages {
+		suite.Assert().Equal(fmt.Sprintf("message %d", i), message, "Records should be written in order")
+	}
+	log.Close()
+	suite.Assert().True(destination.IsClosed(), "Closing the stream should close its destination")
+	suite.Assert().Zero(stream.Dropped())
+}
+
+func (suite *AsyncStreamSuite) TestCanDropNewest() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	stream := &logger.AsyncStream{Destination: destination, QueueSize: 2, OverflowPolicy: logger.OverflowDropNewest}
+	log := logger.Create("test", stream)
+	log.Infof("message 0") // picked up by the background goroutine that waits on the gate
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < 6; i++ {
+		log.Infof("message %d", i)
+	}
+	close(destination.Gate)
+	log.Close()
+	suite.Assert().Equal(uint64(3), stream.Dropped())
+	suite.Assert().Equal([]string{"message 0", "message 1", "message 2"}, destination.Messages())
+}
+
+func (suite *AsyncStreamSuite) TestCanDropOldest() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	stream := &logger.AsyncStream{Destination: destination, QueueSize: 2, OverflowPolicy: logger.OverflowDropOldest}
+	log := logger.Create("test", stream)
+	log.Infof("message 0")
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < 6; i++ {
+		log.Infof("message %d", i)
+	}
+	close(destination.Gate)
+	log.Close()
+	suite.Assert().Equal(uint64(3), stream.Dropped())
+	suite.Assert().Equal([]string{"message 0", "message 4", "message 5"}, destination.Messages())
+}
+
+func (suite *AsyncStreamSuite) TestCanDropBelowLevel() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	stream := &logger.AsyncStream{Destination: destination, QueueSize: 2, OverflowPolicy: logger.OverflowDropBelowLevel, DropBelowLevel: logger.WARN}
+	log := logger.Create("test", stream)
+	log.Infof("message 0")
+	time.Sleep(10 * time.Millisecond)
+	log.Infof("message 1")
+	log.Infof("message 2")
+	log.Infof("message 3") // dropped
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(destination.Gate)
+	}()
+	log.Warnf("message 4") // blocks until the gate opens
+	log.Close()
+	suite.Assert().Equal(uint64(1), stream.Dropped())
+	suite.Assert().Equal([]string{"message 0", "message 1", "message 2", "message 4"}, destination.Messages())
+}
+
+func (suite *AsyncStreamSuite) TestCanBlock() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	stream := &logger.AsyncStream{Destination: destination, QueueSize: 1}
+	log := logger.Create("test", stream)
+	log.Infof("message 0")
+	time.Sleep(10 * time.Millisecond)
+	log.Infof("message 1")
+	written := make(chan struct{})
+	go func() {
+		log.Infof("message 2")
+		close(written)
+	}()
+	select {
+	case <-written:
+		suite.Fail("Write should have blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(destination.Gate)
+	<-written
+	log.Close()
+	suite.Assert().Zero(stream.Dropped())
+	suite.Assert().Equal([]string{"message 0", "message 1", "message 2"}, destination.Messages())
+}
+
+func (suite *AsyncStreamSuite) TestCanFlushWithDeadline() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	defer close(destination.Gate)
+	stream := &logger.AsyncStream{Destination: destination, FlushTimeout: 50 * time.Millisecond}
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("msg", "Hello")))
+	start := time.Now()
+	stream.Flush()
+	suite.Assert().GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+	suite.Assert().Less(time.Since(start), time.Second, "Flush should not wait after its deadline")
+	suite.Assert().Empty(destination.Messages())
+}
+
+func (suite *AsyncStreamSuite) TestFailsWritingAfterClose() {
+	stream := &logger.AsyncStream{Destination: &RecordingStream{}}
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("msg", "Hello")))
+	stream.Close()
+	suite.Require().Error(stream.Write(logger.NewRecord().Set("msg", "Hello")), "Should have failed writing to a closed stream")
+}
+
+func (suite *AsyncStreamSuite) TestShouldCloseDestinationAfterWriting() {
+	destination := &RecordingStream{Gate: make(chan struct{})}
+	stream := &logger.AsyncStream{Destination: destination, FlushTimeout: 50 * time.Millisecond}
+	log := logger.Create("test", stream)
+	log.Infof("message 0") // picked up by the background goroutine that waits on the gate
+	time.Sleep(10 * time.Millisecond)
+	log.Infof("message 1")
+	log.Infof("message 2")
+
+	closed := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	suite.Assert().False(destination.IsClosed(), "The destination should not be closed while a record is being written")
+	close(destination.Gate)
+	<-closed
+
+	suite.Assert().True(destination.IsClosed())
+	suite.Assert().Equal([]string{"message 0"}, destination.Messages())
+	suite.Assert().Equal(uint64(2), stream.Dropped(), "The records still queued after the deadline should be dropped")
+}
+
+func (suite *AsyncStreamSuite) TestShouldNotLoseRecordsWrittenWhileClosing() {
+	destination := &RecordingStream{}
+	stream := &logger.AsyncStream{Destination: destination, QueueSize: 4}
+	var wg sync.WaitGroup
+	var rejected atomic.Uint64
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := stream.Write(logger.NewRecord().Set("msg", "Hello")); err != nil {
+					rejected.Add(1)
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	stream.Close()
+	wg.Wait()
+
+	suite.Assert().Equal(uint64(800), uint64(len(destination.Records()))+stream.Dropped()+rejected.Load(), "Every record should be written, dropped, or rejected")
+}
+
+func (suite *AsyncStreamSuite) TestFailsWritingWithoutDestination() {
+	stream := &logger.AsyncStream{}
+	suite.Require().Error(stream.Write(logger.NewRecord().Set("msg", "Hello")), "Should have failed writing to stream")
+}
+
+func (suite *AsyncStreamSuite) TestShouldNotWriteWithoutDestination() {
+	stream := &logger.AsyncStream{}
+	suite.Assert().False(stream.ShouldWrite(logger.ERROR, "test", "any"), "Should not write without a Destination")
+	suite.Assert().False(stream.ShouldLogSourceInfo())
+	suite.Assert().Empty(stream.GetFilterLevels())
+}
+
+func (suite *AsyncStreamSuite) TestCanCloneAndSetFilterLevel() {
+	stream := &logger.AsyncStream{Destination: &logger.StdoutStream{FilterLevels: logger.NewLevelSet(logger.INFO)}, QueueSize: 10, OverflowPolicy: logger.OverflowDropOldest}
+	suite.Assert().Equal("Async Stream (drop-oldest) to Stream to stdout, Filter: INFO", stream.String())
+	stream.SetFilterLevel(logger.DEBUG, "main")
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "other", ""))
+	stream.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.GetFilterLevels().GetDefault())
+
+	clone := stream.Clone().(*logger.AsyncStream)
+	suite.Assert().Equal(10, clone.QueueSize)
+	suite.Assert().Equal(logger.OverflowDropOldest, clone.OverflowPolicy)
+	suite.Assert().IsType(&logger.StdoutStream{}, clone.Destination)
+	suite.Assert().NotSame(stream.Destination, clone.Destination)
+}
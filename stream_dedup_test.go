@@ -0,0 +1,166 @@
+package logger_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type DedupStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestDedupStreamSuite(t *testing.T) {
+	suite.Run(t, new(DedupStreamSuite))
+}
+
+func (suite *DedupStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *DedupStreamSuite) TestCanCollapseIdenticalRecords() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: time.Hour}
+	log := logger.Create("test", stream)
+	for i := 0; i < 5; i++ {
+		log.Warnf("Disk is almost full")
+	}
+	suite.Assert().Equal([]string{"Disk is almost full"}, destination.Messages())
+
+	log.Warnf("Disk is full")
+	records := destination.Records()
+	suite.Require().Len(records, 3)
+	summary := records[1]
+	suite.Assert().Equal("Disk is almost full", summary.Get("msg"))
+	suite.Assert().Equal(4, summary.Get("repeated"))
+	suite.Assert().IsType(time.Time{}, summary.Get("first_time"))
+	suite.Assert().IsType(time.Time{}, summary.Get("last_time"))
+	suite.Assert().Equal(records[0].Get("time"), summary.Get("first_time"), "first_time should be the time of the first record")
+	suite.Assert().False(summary.Get("last_time").(time.Time).Before(summary.Get("first_time").(time.Time)))
+	suite.Assert().Nil(records[0].Get("repeated"), "The first record should be written as is")
+	suite.Assert().Equal("Disk is full", records[2].Get("msg"))
+}
+
+func (suite *DedupStreamSuite) TestCanTellRecordsApart() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: time.Hour}
+	log := logger.Create("test", stream)
+	log.Warnf("Hello")
+	log.Infof("Hello")
+	log.Child("topic", nil).Infof("Hello")
+	log.Child("topic", "scope").Infof("Hello")
+	log.Child("topic", "scope").Infof("World")
+	suite.Assert().Len(destination.Records(), 5)
+	for _, record := range destination.Records() {
+		suite.Assert().Nil(record.Get("repeated"))
+	}
+}
+
+func (suite *DedupStreamSuite) TestCanCloseWindow() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: 50 * time.Millisecond}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	log.Infof("Hello")
+	log.Infof("Hello")
+	log.Infof("Hello")
+	suite.Require().Eventually(func() bool { return len(destination.Records()) == 2 }, time.Second, 10*time.Millisecond, "The summary should be written when the window closes")
+	suite.Assert().Equal(2, destination.Records()[1].Get("repeated"))
+
+	log.Infof("Hello")
+	suite.Require().Len(destination.Records(), 3, "A new window should start after the previous one closed")
+	suite.Assert().Nil(destination.Records()[2].Get("repeated"))
+}
+
+func (suite *DedupStreamSuite) TestCanFlushAndClose() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: time.Hour}
+	log := logger.Create("test", stream)
+	log.Infof("Hello")
+	log.Infof("Hello")
+	log.Flush()
+	suite.Require().Len(destination.Records(), 2)
+	suite.Assert().Equal(1, destination.Records()[1].Get("repeated"))
+
+	log.Infof("Hello")
+	log.Infof("Hello")
+	log.Infof("Hello")
+	log.Close()
+	suite.Require().Len(destination.Records(), 4)
+	suite.Assert().Equal(2, destination.Records()[3].Get("repeated"))
+	suite.Assert().True(destination.IsClosed())
+}
+
+func (suite *DedupStreamSuite) TestCanCollapseAfterFlush() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: time.Hour}
+	log := logger.Create("test", stream)
+	log.Infof("Hello")
+	log.Flush()
+	log.Infof("Hello")
+	suite.Require().Len(destination.Records(), 1, "Flushing without collapsed records should not end the window")
+
+	log.Flush()
+	records := destination.Records()
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal(1, records[1].Get("repeated"))
+	suite.Assert().Equal(records[0].Get("time"), records[1].Get("first_time"))
+}
+
+func (suite *DedupStreamSuite) TestShouldNotLoseRecordsWhenWindowClosesWhileWriting() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination, Window: 5 * time.Millisecond}
+	log := logger.Create("test", stream)
+	written := 0
+	for end := time.Now().Add(100 * time.Millisecond); time.Now().Before(end); written++ {
+		log.Infof("Hello")
+		time.Sleep(100 * time.Microsecond)
+	}
+	log.Close()
+
+	count := 0
+	var first *logger.Record
+	for _, record := range destination.Records() {
+		repeated, collapsed := record.Get("repeated").(int)
+		if !collapsed {
+			first = record
+			count++
+			continue
+		}
+		suite.Require().NotNil(first, "A collapsed record should follow the record it collapses")
+		suite.Assert().Equal(first.Get("time"), record.Get("first_time"))
+		suite.Assert().False(record.Get("last_time").(time.Time).Before(record.Get("first_time").(time.Time)))
+		count += repeated
+		first = nil
+	}
+	suite.Assert().Equal(written, count, "Every record should be written or collapsed")
+}
+
+func (suite *DedupStreamSuite) TestShouldNotWriteWithoutDestination() {
+	stream := &logger.DedupStream{}
+	suite.Assert().False(stream.ShouldWrite(logger.ERROR, "test", "any"), "Should not write without a Destination")
+	suite.Assert().False(stream.ShouldLogSourceInfo())
+	suite.Assert().Empty(stream.GetFilterLevels())
+}
+
+func (suite *DedupStreamSuite) TestShouldCollapseRecordsWithDifferentValues() {
+	destination := &RecordingStream{}
+	stream := &logger.DedupStream{Destination: destination}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	log.Record("attempt", 1).Warnf("Connection refused")
+	log.Record("attempt", 2).Warnf("Connection refused")
+	log.Record("attempt", 3).Warnf("Connection refused")
+	log.Flush()
+
+	records := destination.Records()
+	suite.Require().Len(records, 2, "Only the level, topic, scope, and message should tell records apart")
+	suite.Assert().Equal(1, records[0].Get("attempt"))
+	suite.Assert().Equal(2, records[1].Get("repeated"))
+}
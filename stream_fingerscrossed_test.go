@@ -0,0 +1,157 @@
+package logger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type FingersCrossedStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestFingersCrossedStreamSuite(t *testing.T) {
+	suite.Run(t, new(FingersCrossedStreamSuite))
+}
+
+func (suite *FingersCrossedStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *FingersCrossedStreamSuite) TestShouldNotWriteWithoutDestination() {
+	stream := &logger.FingersCrossedStream{}
+	suite.Assert().False(stream.ShouldWrite(logger.ERROR, "test", "any"), "Should not write without a Destination")
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "test", "any"), "Should not buffer without a Destination")
+	suite.Assert().False(stream.ShouldLogSourceInfo())
+	suite.Assert().Empty(stream.GetFilterLevels())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanDumpBufferOnError() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination}
+	log := logger.Create("test", stream)
+	request1 := log.Records("reqid", "1")
+	request2 := log.Records("reqid", "2")
+
+	request1.Tracef("request 1 trace")
+	request2.Debugf("request 2 debug")
+	request1.Debugf("request 1 debug")
+	request1.Infof("request 1 info")
+	suite.Assert().Equal([]string{"request 1 info"}, destination.Messages())
+
+	request1.Errorf("request 1 error")
+	suite.Assert().Equal([]string{"request 1 info", "request 1 trace", "request 1 debug", "request 1 error"}, destination.Messages())
+
+	request1.Debugf("request 1 debug after error")
+	suite.Assert().Equal("request 1 debug after error", destination.Messages()[4], "Records should be written once the key was activated")
+	suite.Assert().Len(destination.Messages(), 5, "Records of the other key should still be buffered")
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanDiscardBufferOnRelease() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination}
+	log := logger.Create("test", stream)
+
+	log.Records("reqid", "1").Debugf("debug 1")
+	log.Records("reqid", "1").Record("http_status", 200).Infof("request finish")
+	log.Records("reqid", "1").Errorf("error 1")
+	suite.Assert().Equal([]string{"request finish", "error 1"}, destination.Messages())
+
+	log.Records("reqid", "2").Debugf("debug 2")
+	stream.Discard("2")
+	log.Records("reqid", "2").Errorf("error 2")
+	suite.Assert().Equal([]string{"request finish", "error 1", "error 2"}, destination.Messages())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanWriteRecordsWithoutKey() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination, Key: "session"}
+	log := logger.Create("test", stream)
+	suite.Assert().True(log.ShouldWrite(logger.TRACE, "main", "main"), "The stream should accept all levels")
+
+	log.Debugf("debug")
+	log.Infof("info")
+	log.Errorf("error")
+	suite.Assert().Equal([]string{"info", "error"}, destination.Messages())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanLimitBuffer() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination, MaxRecords: 2, ActivationLevel: logger.WARN}
+	log := logger.Create("test", stream).Records("reqid", "1")
+	log.Debugf("debug 1")
+	log.Debugf("debug 2")
+	log.Debugf("debug 3")
+	log.Warnf("warning")
+	suite.Assert().Equal([]string{"debug 2", "debug 3", "warning"}, destination.Messages())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanPurgeIdleBuffers() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination, Timeout: 20 * time.Millisecond}
+	log := logger.Create("test", stream)
+	log.Records("reqid", "1").Debugf("debug 1")
+	time.Sleep(30 * time.Millisecond)
+	log.Records("reqid", "2").Debugf("debug 2")
+	log.Records("reqid", "1").Errorf("error 1")
+	suite.Assert().Equal([]string{"error 1"}, destination.Messages())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanDumpFailingRequests() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	log := logger.Create("test", &logger.FingersCrossedStream{Destination: destination})
+	handler := log.HttpHandler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Must(logger.FromContext(r.Context()))
+		log.Debugf("handling %s", r.URL.Path)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/succeed", nil))
+	suite.Require().Len(destination.Messages(), 2)
+	suite.Assert().NotContains(destination.Messages(), "handling /succeed")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	messages := destination.Messages()
+	suite.Require().Len(messages, 5)
+	suite.Assert().Equal("handling /fail", messages[3], "The debug records of the failing request should be written before the error")
+	suite.Assert().Contains(messages[4], "request finish: GET /fail")
+}
+
+func (suite *FingersCrossedStreamSuite) TestShouldNotBufferWhatTheDestinationNeverWrites() {
+	destination := &RecordingStream{FilterLevels: logger.ParseLevels("INFO;NEVER:{noisy}")}
+	stream := &logger.FingersCrossedStream{Destination: destination}
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "noisy", ""))
+	suite.Assert().True(stream.ShouldWrite(logger.ERROR, "noisy", ""), "The activation level should be accepted")
+
+	log := logger.Create("test", stream).Records("reqid", "1")
+	log.Debugf("debug")
+	log.Child("noisy", nil).Debugf("noisy debug")
+	log.Errorf("error")
+	suite.Assert().Equal([]string{"debug", "error"}, destination.Messages())
+}
+
+func (suite *FingersCrossedStreamSuite) TestCanBufferOnlySomeLevels() {
+	destination := &RecordingStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream := &logger.FingersCrossedStream{Destination: destination, BufferLevels: logger.NewLevelSet(logger.DEBUG)}
+	suite.Assert().False(stream.ShouldWrite(logger.TRACE, "main", ""))
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+
+	log := logger.Create("test", stream).Records("reqid", "1")
+	log.Tracef("trace")
+	log.Debugf("debug")
+	log.Errorf("error")
+	suite.Assert().Equal([]string{"debug", "error"}, destination.Messages())
+}
@@ -2,7 +2,10 @@ package logger
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,3 +27,85 @@ func TestMultiStreamCanSetFilterLevel(t *testing.T) {
 	assert.Equal(t, WARN, stream.streams[0].(*StdoutStream).FilterLevels.GetDefault())
 	assert.Equal(t, WARN, stream.streams[1].(*StderrStream).FilterLevels.GetDefault())
 }
+
+func TestCanParseSize(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1024":  1024,
+		"512B":  512,
+		"10KB":  10 * 1000,
+		"10kib": 10 * 1024,
+		"10K":   10 * 1024,
+		"100MB": 100 * 1000 * 1000,
+		"1 GiB": 1024 * 1024 * 1024,
+		"2M":    2 * 1024 * 1024,
+		" 3gb ": 3 * 1000 * 1000 * 1000,
+	} {
+		size, err := parseSize(value)
+		assert.NoError(t, err, "Failed to parse %s", value)
+		assert.Equal(t, expected, size, "Wrong size for %s", value)
+	}
+	_, err := parseSize("lots")
+	assert.Error(t, err)
+	_, err = parseSize("-12MB")
+	assert.Error(t, err)
+}
+
+func TestCanParseRotationInterval(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"hourly": time.Hour,
+		"Daily":  24 * time.Hour,
+		"weekly": 7 * 24 * time.Hour,
+		"7d":     7 * 24 * time.Hour,
+		"30m":    30 * time.Minute,
+		"PT6H":   6 * time.Hour,
+		"P1W":    7 * 24 * time.Hour,
+	} {
+		interval, err := parseRotationInterval(value)
+		assert.NoError(t, err, "Failed to parse %s", value)
+		assert.Equal(t, expected, interval, "Wrong interval for %s", value)
+	}
+	_, err := parseRotationInterval("sometimes")
+	assert.Error(t, err)
+}
+
+func TestFileStreamShouldReopenFileWhenCloseFails(t *testing.T) {
+	stream := &FileStream{Path: filepath.Join(t.TempDir(), "test.log"), MaxSize: 20, Unbuffered: true}
+	defer stream.Close()
+	assert.NoError(t, stream.Write(NewRecord().Set("index", 1)))
+
+	// Closing the file beforehand makes the rotation fail when closing it
+	_ = stream.file.Close()
+	stderr := os.Stderr
+	defer func() { os.Stderr = stderr }()
+	os.Stderr, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assert.NoError(t, stream.Write(NewRecord().Set("index", 2)), "The record should be written to the reopened file")
+	assert.NoError(t, stream.Write(NewRecord().Set("index", 3)))
+
+	content, err := os.ReadFile(stream.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(content), "\n"), "No record should have been lost")
+}
+
+func TestCanCreateFileStreamWithQuestionMarkInPath(t *testing.T) {
+	assert.Equal(t, "/var/log/what?.log", createFileStream("file:///var/log/what?.log").Path)
+	assert.Equal(t, "/var/log/a?b=c.log", createFileStream("/var/log/a?b=c.log").Path, "Unknown options should be part of the path")
+	stream := createFileStream("/var/log/what?.log?maxsize=10KB")
+	assert.Equal(t, "/var/log/what?.log", stream.Path)
+	assert.Equal(t, int64(10*1000), stream.MaxSize)
+}
+
+func TestDedupStreamShouldIgnoreStaleWindowClose(t *testing.T) {
+	var output strings.Builder
+	stream := &DedupStream{Destination: &WriterStream{Writer: &output, Unbuffered: true}, Window: time.Hour}
+	defer stream.Close()
+	assert.NoError(t, stream.Write(NewRecord().Set("msg", "Hello")))
+	assert.NoError(t, stream.Write(NewRecord().Set("msg", "Hello")))
+
+	// A timer of a previous window that fired while waiting for the mutex
+	stream.closeWindow(stream.window - 1)
+	assert.Equal(t, 1, strings.Count(output.String(), "\n"), "A previous window should not close the current one")
+
+	stream.closeWindow(stream.window)
+	assert.Equal(t, 2, strings.Count(output.String(), "\n"), "The current window should be closed")
+	assert.Contains(t, output.String(), `"repeated":1`)
+}
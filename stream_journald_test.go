@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+package logger_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type JournaldStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestJournaldStreamSuite(t *testing.T) {
+	suite.Run(t, new(JournaldStreamSuite))
+}
+
+func (suite *JournaldStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// listen creates a fake journald socket
+func (suite *JournaldStreamSuite) listen() (*net.UnixConn, string, func()) {
+	folder, teardown := CreateTempDir()
+	socket := filepath.Join(folder, "journal.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	suite.Require().NoError(err)
+	return server, socket, func() {
+		server.Close()
+		teardown()
+	}
+}
+
+// parseJournalFields parses a payload written with the journald native protocol
+func (suite *JournaldStreamSuite) parseJournalFields(payload []byte) map[string]string {
+	fields := map[string]string{}
+	for len(payload) > 0 {
+		end := bytes.IndexByte(payload, '\n')
+		suite.Require().GreaterOrEqual(end, 0, "Missing newline in payload")
+		line := payload[:end]
+		if equal := bytes.IndexByte(line, '='); equal >= 0 {
+			fields[string(line[:equal])] = string(line[equal+1:])
+			payload = payload[end+1:]
+			continue
+		}
+		payload = payload[end+1:]
+		suite.Require().GreaterOrEqual(len(payload), 8, "Missing value length in payload")
+		length := int(binary.LittleEndian.Uint64(payload[:8]))
+		suite.Require().GreaterOrEqual(len(payload), 8+length+1, "Value is too short in payload")
+		fields[string(line)] = string(payload[8 : 8+length])
+		suite.Require().Equal(byte('\n'), payload[8+length], "Missing newline after binary value")
+		payload = payload[8+length+1:]
+	}
+	return fields
+}
+
+func (suite *JournaldStreamSuite) TestCanCreateFromDestination() {
+	stream := logger.CreateStream(logger.NewLevelSet(logger.INFO), "journald")
+	suite.Require().IsType(&logger.JournaldStream{}, stream)
+	suite.Assert().Equal("Stream to journald at /run/systemd/journal/socket, Filter: INFO", fmt.Sprintf("%s", stream))
+
+	stream = logger.CreateStream(logger.NewLevelSet(logger.INFO), "journald:///tmp/journal.sock")
+	suite.Require().IsType(&logger.JournaldStream{}, stream)
+	suite.Assert().Equal("/tmp/journal.sock", stream.(*logger.JournaldStream).SocketPath)
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), stream.GetFilterLevels())
+
+	clone := stream.Clone().(*logger.JournaldStream)
+	suite.Assert().Equal("/tmp/journal.sock", clone.SocketPath)
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), clone.GetFilterLevels())
+}
+
+func (suite *JournaldStreamSuite) TestCanStreamToJournald() {
+	server, socket, teardown := suite.listen()
+	defer teardown()
+
+	stream := &logger.JournaldStream{SocketPath: socket, SourceInfo: true}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	log.Record("request-id", "1234").Record("stack", "line 1\nline 2").Warnf("Hello World")
+
+	buffer := make([]byte, 65536)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	length, err := server.Read(buffer)
+	suite.Require().NoError(err)
+	fields := suite.parseJournalFields(buffer[:length])
+	suite.T().Logf("Fields: %v", fields)
+	suite.Assert().Equal("4", fields["PRIORITY"])
+	suite.Assert().Equal("Hello World", fields["MESSAGE"])
+	suite.Assert().Equal("test", fields["SYSLOG_IDENTIFIER"])
+	suite.Assert().Equal("1234", fields["REQUEST_ID"])
+	suite.Assert().Equal("line 1\nline 2", fields["STACK"])
+	suite.Assert().Equal("main", fields["TOPIC"])
+	suite.Assert().Contains(fields["CODE_FILE"], "stream_journald_test.go")
+	suite.Assert().NotEmpty(fields["CODE_LINE"])
+	suite.Assert().Contains(fields["CODE_FUNC"], "TestCanStreamToJournald")
+	suite.Assert().NotContains(fields, "LEVEL")
+}
+
+func (suite *JournaldStreamSuite) TestCanStreamLargeRecordsToJournald() {
+	server, socket, teardown := suite.listen()
+	defer teardown()
+
+	stream := &logger.JournaldStream{SocketPath: socket}
+	defer stream.Close()
+	message := strings.Repeat("x", 1024*1024)
+	err := stream.Write(logger.NewRecord().Set("level", logger.INFO).Set("msg", message))
+	suite.Require().NoError(err)
+
+	buffer := make([]byte, 1024)
+	control := make([]byte, syscall.CmsgSpace(4))
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	_, controlLength, _, _, err := server.ReadMsgUnix(buffer, control)
+	suite.Require().NoError(err)
+	messages, err := syscall.ParseSocketControlMessage(control[:controlLength])
+	suite.Require().NoError(err)
+	suite.Require().Len(messages, 1)
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	suite.Require().NoError(err)
+	suite.Require().Len(fds, 1)
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+	_, err = file.Seek(0, io.SeekStart)
+	suite.Require().NoError(err)
+	payload, err := io.ReadAll(file)
+	suite.Require().NoError(err)
+	fields := suite.parseJournalFields(payload)
+	suite.Assert().Equal("6", fields["PRIORITY"])
+	suite.Assert().Equal(message, fields["MESSAGE"])
+}
+
+func (suite *JournaldStreamSuite) TestCanSanitizeFieldNames() {
+	server, socket, teardown := suite.listen()
+	defer teardown()
+
+	stream := &logger.JournaldStream{SocketPath: socket}
+	defer stream.Close()
+	err := stream.Write(logger.NewRecord().Set("level", logger.ERROR).Set("_private", "a").Set("1st", "b").Set("some.key", "c").Set(strings.Repeat("k", 100), "d"))
+	suite.Require().NoError(err)
+
+	buffer := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	length, err := server.Read(buffer)
+	suite.Require().NoError(err)
+	fields := suite.parseJournalFields(buffer[:length])
+	suite.Assert().Equal("3", fields["PRIORITY"])
+	suite.Assert().Equal("a", fields["PRIVATE"])
+	suite.Assert().Equal("b", fields["X_1ST"])
+	suite.Assert().Equal("c", fields["SOME_KEY"])
+	suite.Assert().Equal("d", fields[strings.Repeat("K", 64)])
+}
+
+func (suite *JournaldStreamSuite) TestFailsWritingToMissingSocket() {
+	stream := &logger.JournaldStream{SocketPath: "/path/to/nowhere.sock"}
+	err := stream.Write(logger.NewRecord().Set("msg", "Hello"))
+	suite.Require().Error(err, "Should have failed writing to stream")
+}
+
+func (suite *JournaldStreamSuite) TestCanSetFilterLevel() {
+	stream := &logger.JournaldStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream.SetFilterLevel(logger.DEBUG, "main")
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "other", ""))
+	stream.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault())
+	stream.FilterLess()
+	suite.Assert().Equal(logger.INFO, stream.FilterLevels.GetDefault())
+}
+
+func (suite *JournaldStreamSuite) TestShouldUseEnvironmentPrefixForDefaultLevels() {
+	_ = os.Setenv("JOURNALDTEST_LOG_LEVEL", "WARN")
+	defer func() { _ = os.Unsetenv("JOURNALDTEST_LOG_LEVEL") }()
+
+	stream := logger.CreateStreamWithPrefix("JOURNALDTEST_", logger.LevelSet{}, "journald:///path/to/nowhere.sock")
+	suite.Require().IsType(&logger.JournaldStream{}, stream)
+	_ = stream.Write(logger.NewRecord().Set("msg", "Hello"))
+	suite.Assert().Equal(logger.NewLevelSet(logger.WARN), stream.GetFilterLevels())
+	suite.Assert().Equal(logger.NewLevelSet(logger.WARN), stream.Clone().(*logger.JournaldStream).GetFilterLevels())
+}
@@ -0,0 +1,153 @@
+package logger_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type RingBufferStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestRingBufferStreamSuite(t *testing.T) {
+	suite.Run(t, new(RingBufferStreamSuite))
+}
+
+func (suite *RingBufferStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// get queries the stream and returns the messages it served
+func (suite *RingBufferStreamSuite) get(handler http.Handler, query string) (messages []string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/logs"+query, nil))
+	suite.Require().Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	suite.Require().Equal("application/x-ndjson", recorder.Header().Get("Content-Type"))
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		var record map[string]any
+		suite.Require().NoError(json.Unmarshal(scanner.Bytes(), &record), "Each line should be a JSON object")
+		messages = append(messages, record["msg"].(string))
+	}
+	return
+}
+
+func (suite *RingBufferStreamSuite) TestCanKeepLastRecords() {
+	stream := &logger.RingBufferStream{MaxRecords: 3}
+	log := logger.Create("test", stream)
+	for i := 1; i <= 5; i++ {
+		log.Infof("message %d", i)
+	}
+	suite.Assert().Equal(3, stream.Len())
+	suite.Assert().Equal([]string{"message 3", "message 4", "message 5"}, suite.get(stream, ""))
+}
+
+func (suite *RingBufferStreamSuite) TestCanKeepLastBytes() {
+	record := func(i int) *logger.Record {
+		return logger.NewRecord().Set("level", logger.INFO).Set("msg", fmt.Sprintf("message %d", i))
+	}
+	probe := &logger.RingBufferStream{}
+	suite.Require().NoError(probe.Write(record(0)))
+	recorder := httptest.NewRecorder()
+	probe.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/logs", nil))
+	size := int64(recorder.Body.Len() - 1) // without the newline
+
+	stream := &logger.RingBufferStream{MaxBytes: 2*size + size/2}
+	for i := 1; i <= 5; i++ {
+		suite.Require().NoError(stream.Write(record(i)))
+	}
+	suite.Assert().Equal([]string{"message 4", "message 5"}, suite.get(stream, ""))
+
+	stream = &logger.RingBufferStream{MaxBytes: 1}
+	suite.Require().NoError(stream.Write(record(1)))
+	suite.Require().NoError(stream.Write(record(2)))
+	suite.Assert().Equal([]string{"message 2"}, suite.get(stream, ""), "The last record should always be kept")
+}
+
+func (suite *RingBufferStreamSuite) TestCanFilterRecords() {
+	stream := &logger.RingBufferStream{}
+	log := logger.Create("test", stream)
+	log.Debugf("debug")
+	log.Infof("info")
+	log.Child("http", "request").Warnf("http warning")
+	log.Child("http", "response").Errorf("http error")
+	log.Child("db", nil).Errorf("db error")
+
+	suite.Assert().Equal([]string{"http warning", "http error", "db error"}, suite.get(stream, "?level=warn"))
+	suite.Assert().Equal([]string{"http warning", "http error"}, suite.get(stream, "?topic=http"))
+	suite.Assert().Equal([]string{"http error"}, suite.get(stream, "?topic=http&scope=response"))
+	suite.Assert().Equal([]string{"http error", "db error"}, suite.get(stream, "?level=ERROR&limit=5"))
+	suite.Assert().Equal([]string{"db error"}, suite.get(stream, "?level=ERROR&limit=1"))
+	suite.Assert().Len(suite.get(stream, "?since=1m"), 5)
+	suite.Assert().Empty(suite.get(stream, "?until="+time.Now().Add(-time.Minute).Format(time.RFC3339)))
+	suite.Assert().Len(suite.get(stream, "?since="+time.Now().Add(-time.Minute).Format(time.RFC3339)+"&until="+time.Now().Add(time.Minute).Format(time.RFC3339)), 5)
+}
+
+func (suite *RingBufferStreamSuite) TestFailsServingWithInvalidQuery() {
+	stream := &logger.RingBufferStream{}
+	for _, query := range []string{"?level=bogus", "?level=never", "?level=unset", "?since=yesterday", "?until=tomorrow", "?limit=-1", "?limit=many"} {
+		recorder := httptest.NewRecorder()
+		stream.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/logs"+query, nil))
+		suite.Assert().Equal(http.StatusBadRequest, recorder.Code, "Query %s should be rejected", query)
+	}
+	recorder := httptest.NewRecorder()
+	stream.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/logs", nil))
+	suite.Assert().Equal(http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func (suite *RingBufferStreamSuite) TestCanWriteAndReadConcurrently() {
+	stream := &logger.RingBufferStream{MaxRecords: 50}
+	log := logger.Create("test", logger.CreateMultiStream(&logger.NilStream{}, stream))
+	var waitgroup sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		waitgroup.Add(1)
+		go func(writer int) {
+			defer waitgroup.Done()
+			for i := 0; i < 100; i++ {
+				log.Infof("writer %d message %d", writer, i)
+			}
+		}(writer)
+	}
+	for reader := 0; reader < 2; reader++ {
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			for i := 0; i < 20; i++ {
+				recorder := httptest.NewRecorder()
+				stream.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/logs?level=info", nil))
+			}
+		}()
+	}
+	waitgroup.Wait()
+	suite.Assert().Equal(50, stream.Len())
+}
+
+func (suite *RingBufferStreamSuite) TestCanCloneAndSetFilterLevel() {
+	stream := &logger.RingBufferStream{FilterLevels: logger.NewLevelSet(logger.INFO), MaxRecords: 10}
+	suite.Assert().Equal("Stream to ring buffer, Filter: INFO", stream.String())
+	stream.SetFilterLevel(logger.DEBUG, "main")
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "other", ""))
+	stream.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault())
+
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("msg", "Hello")))
+	clone := stream.Clone().(*logger.RingBufferStream)
+	suite.Assert().Equal(10, clone.MaxRecords)
+	suite.Assert().Zero(clone.Len(), "Records should not be cloned")
+	stream.Close()
+	suite.Assert().Zero(stream.Len())
+}
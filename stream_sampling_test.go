@@ -0,0 +1,111 @@
+package logger_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type SamplingStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestSamplingStreamSuite(t *testing.T) {
+	suite.Run(t, new(SamplingStreamSuite))
+}
+
+func (suite *SamplingStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *SamplingStreamSuite) TestShouldNotWriteWithoutDestination() {
+	stream := &logger.SamplingStream{}
+	suite.Assert().False(stream.ShouldWrite(logger.ERROR, "test", "any"), "Should not write without a Destination")
+	suite.Assert().False(stream.ShouldLogSourceInfo())
+	suite.Assert().Empty(stream.GetFilterLevels())
+}
+
+func (suite *SamplingStreamSuite) TestCanSample() {
+	destination := &RecordingStream{}
+	stream := &logger.SamplingStream{Destination: destination, First: 3, Thereafter: 5, Tick: time.Hour}
+	log := logger.Create("test", stream)
+	for i := 1; i <= 20; i++ {
+		log.Infof("message %d", i)
+	}
+	suite.Assert().Equal([]string{"message 1", "message 2", "message 3", "message 8", "message 13", "message 18"}, destination.Messages())
+}
+
+func (suite *SamplingStreamSuite) TestCanSampleWithDefaults() {
+	destination := &RecordingStream{}
+	stream := &logger.SamplingStream{Destination: destination}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	for i := 1; i <= 250; i++ {
+		log.Infof("message %d", i)
+	}
+	messages := destination.Messages()
+	suite.Require().Len(messages, 101, "The first 100 records, then every 100th record should be written")
+	suite.Assert().Equal("message 100", messages[99])
+	suite.Assert().Equal("message 200", messages[100])
+}
+
+func (suite *SamplingStreamSuite) TestCanSamplePerLevelTopicScopeAndTemplate() {
+	destination := &RecordingStream{}
+	stream := &logger.SamplingStream{Destination: destination, First: 1, Thereafter: -1, Tick: time.Hour}
+	log := logger.Create("test", stream)
+	for i := 1; i <= 3; i++ {
+		log.Infof("message %d", i)
+		log.Debugf("message %d", i)
+		log.Infof("other message %d", i)
+		log.Child("topic", "scope").Infof("message %d", i)
+		log.Errorf("error %d", i)
+	}
+	suite.Assert().Equal([]string{
+		"message 1", "message 1", "other message 1", "message 1", "error 1",
+		"error 2",
+		"error 3",
+	}, destination.Messages())
+}
+
+func (suite *SamplingStreamSuite) TestCanSummarizeSuppressedRecords() {
+	destination := &RecordingStream{}
+	stream := &logger.SamplingStream{Destination: destination, First: 2, Thereafter: -1, Tick: 50 * time.Millisecond}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	for i := 1; i <= 10; i++ {
+		log.Child("topic", "scope").Debugf("message %d", i)
+	}
+	suite.Require().Len(destination.Records(), 2)
+
+	suite.Require().Eventually(func() bool { return len(destination.Records()) == 3 }, time.Second, 10*time.Millisecond, "A summary should be written at the end of the tick")
+	summary := destination.Records()[2]
+	suite.Assert().Equal(logger.WARN, summary.Get("level"))
+	suite.Assert().Equal(8, summary.Get("suppressed"))
+	suite.Assert().Equal("topic", summary.Get("topic"))
+	suite.Assert().Equal("scope", summary.Get("scope"))
+	suite.Assert().Equal("Suppressed 8 DEBUG records: message %d", summary.Get("msg"))
+
+	log.Child("topic", "scope").Debugf("message %d", 11)
+	suite.Assert().Equal("message 11", destination.Messages()[3], "Records should be sampled again after a tick")
+}
+
+func (suite *SamplingStreamSuite) TestCanSummarizeOnFlush() {
+	destination := &RecordingStream{}
+	stream := &logger.SamplingStream{Destination: destination, First: 1, Thereafter: -1, Tick: time.Hour}
+	log := logger.Create("test", stream)
+	for i := 1; i <= 5; i++ {
+		log.Infof("message %d", i)
+	}
+	log.Flush()
+	suite.Require().Len(destination.Records(), 2)
+	suite.Assert().Equal(4, destination.Records()[1].Get("suppressed"))
+	log.Close()
+	suite.Assert().Len(destination.Records(), 2, "There should be no summary when nothing was suppressed")
+	suite.Assert().True(destination.IsClosed())
+}
@@ -0,0 +1,140 @@
+package logger_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type SlogStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestSlogStreamSuite(t *testing.T) {
+	suite.Run(t, new(SlogStreamSuite))
+}
+
+func (suite *SlogStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+// lines unmarshals the JSON lines written by a slog.JSONHandler
+func (suite *SlogStreamSuite) lines(buffer *bytes.Buffer) []map[string]any {
+	lines := []map[string]any{}
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		line := map[string]any{}
+		suite.Require().NoError(json.Unmarshal(scanner.Bytes(), &line), "Each line should be a JSON object")
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (suite *SlogStreamSuite) TestCanWriteToSlogHandler() {
+	var buffer bytes.Buffer
+	log := logger.Create("test", &logger.SlogStream{Handler: slog.NewJSONHandler(&buffer, nil)})
+	log.Record("user", "john").Infof("Hello %s", "World")
+
+	lines := suite.lines(&buffer)
+	suite.Require().Len(lines, 1)
+	suite.Assert().Equal("Hello World", lines[0]["msg"])
+	suite.Assert().Equal("INFO", lines[0]["level"])
+	suite.Assert().Equal("john", lines[0]["user"])
+	suite.Assert().Equal("test", lines[0]["name"])
+	suite.Assert().Equal("main", lines[0]["topic"])
+	suite.Assert().Equal("main", lines[0]["scope"])
+	suite.Assert().NotEmpty(lines[0]["time"])
+	suite.Assert().NotNil(lines[0]["tid"], "funcs should be called")
+}
+
+func (suite *SlogStreamSuite) TestCanWriteToTextHandler() {
+	var buffer bytes.Buffer
+	log := logger.Create("test", &logger.SlogStream{Handler: slog.NewTextHandler(&buffer, nil)})
+	log.Record("user", "john").Warnf("Hello")
+	suite.Assert().Regexp(`^time=\S+ level=WARN msg=Hello hostname=\S+ name=test pid=\d+ scope=main tid=\d+ topic=main user=john v=0\n$`, buffer.String())
+}
+
+func (suite *SlogStreamSuite) TestCanMapLevels() {
+	var buffer bytes.Buffer
+	log := logger.Create("test", &logger.SlogStream{Handler: slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug - 4})})
+	log.Tracef("trace")
+	log.Debugf("debug")
+	log.Infof("info")
+	log.Warnf("warn")
+	log.Errorf("error")
+	log.Fatalf("fatal")
+
+	expected := []string{"DEBUG-4", "DEBUG", "INFO", "WARN", "ERROR", "ERROR+4"}
+	lines := suite.lines(&buffer)
+	suite.Require().Len(lines, len(expected))
+	for i, level := range expected {
+		suite.Assert().Equal(level, lines[i]["level"], "Record %s has the wrong level", lines[i]["msg"])
+	}
+}
+
+func (suite *SlogStreamSuite) TestCanFilterRecords() {
+	var buffer bytes.Buffer
+	levels := logger.NewLevelSet(logger.WARN)
+	levels.Set(logger.TRACE, "http", "")
+	stream := &logger.SlogStream{Handler: slog.NewJSONHandler(&buffer, nil), FilterLevels: levels}
+	suite.Assert().False(stream.ShouldWrite(logger.INFO, "main", "main"), "The FilterLevels should be used")
+	suite.Assert().True(stream.ShouldWrite(logger.INFO, "http", "main"))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "http", "main"), "The Handler's level should be used")
+
+	log := logger.Create("test", stream)
+	log.Infof("main info")
+	log.Warnf("main warn")
+	log.Child("http", nil).Debugf("http debug")
+	log.Child("http", nil).Infof("http info")
+	lines := suite.lines(&buffer)
+	suite.Require().Len(lines, 2)
+	suite.Assert().Equal("main warn", lines[0]["msg"])
+	suite.Assert().Equal("http info", lines[1]["msg"])
+}
+
+func (suite *SlogStreamSuite) TestCanRedactValues() {
+	var buffer bytes.Buffer
+	log := logger.Create("test", &logger.SlogStream{Handler: slog.NewJSONHandler(&buffer, nil)})
+	log.
+		Record("customer", User{"12345678", "John Doe", nil}).
+		RecordWithKeysToRedact("metadata", Metadata{UserID: "12345678", Name: "John Doe", City: "Tokyo"}, "name", "city").
+		Record("empty", "").
+		Record("?shown", "").
+		Infof("message")
+
+	lines := suite.lines(&buffer)
+	suite.Require().Len(lines, 1)
+	suite.Assert().Equal(map[string]any{"id": "12345678", "name": "REDACTED"}, lines[0]["customer"])
+	suite.Assert().Equal(map[string]any{"userId": "12345678", "name": "REDACTED", "city": "REDACTED"}, lines[0]["metadata"])
+	suite.Assert().NotContains(lines[0], "empty", "Empty values should be skipped")
+	suite.Assert().Contains(lines[0], "shown", "Empty values with a ? key should be written")
+}
+
+func (suite *SlogStreamSuite) TestFailsWritingWithoutHandler() {
+	stream := &logger.SlogStream{}
+	suite.Require().Error(stream.Write(logger.NewRecord().Set("msg", "Hello")), "Should have failed writing to stream")
+}
+
+func (suite *SlogStreamSuite) TestCanCloneAndSetFilterLevel() {
+	stream := &logger.SlogStream{Handler: slog.NewTextHandler(&bytes.Buffer{}, nil), FilterLevels: logger.NewLevelSet(logger.INFO)}
+	suite.Assert().Equal("Stream to slog handler *slog.TextHandler, Filter: INFO", stream.String())
+	stream.SetFilterLevel(logger.WARN, "main")
+	suite.Assert().Equal(logger.WARN, stream.GetFilterLevels().Get("main", ""))
+	stream.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault())
+
+	clone := stream.Clone().(*logger.SlogStream)
+	suite.Assert().Same(stream.Handler, clone.Handler)
+	clone.FilterLess()
+	suite.Assert().Equal(logger.INFO, clone.FilterLevels.GetDefault())
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault(), "The clone should be independent")
+}
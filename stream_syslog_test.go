@@ -0,0 +1,175 @@
+package logger_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+type SyslogStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+func TestSyslogStreamSuite(t *testing.T) {
+	suite.Run(t, new(SyslogStreamSuite))
+}
+
+func (suite *SyslogStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *SyslogStreamSuite) TestCanCreateFromDestination() {
+	stream := logger.CreateStream(logger.NewLevelSet(logger.INFO), "syslog")
+	suite.Require().IsType(&logger.SyslogStream{}, stream)
+	suite.Assert().Equal("Stream to syslog at /dev/log, Filter: INFO", fmt.Sprintf("%s", stream))
+
+	stream = logger.CreateStream(logger.NewLevelSet(logger.INFO), "syslog:///var/run/syslog")
+	suite.Require().IsType(&logger.SyslogStream{}, stream)
+	suite.Assert().Equal("unix:///var/run/syslog", stream.(*logger.SyslogStream).Address)
+
+	stream = logger.CreateStream(logger.NewLevelSet(logger.INFO), "syslog://localhost:514")
+	suite.Require().IsType(&logger.SyslogStream{}, stream)
+	suite.Assert().Equal("udp://localhost:514", stream.(*logger.SyslogStream).Address)
+
+	stream = logger.CreateStream(logger.NewLevelSet(logger.INFO), "syslog+tcp://localhost:6514?format=rfc3164&facility=local0&app=myapp")
+	suite.Require().IsType(&logger.SyslogStream{}, stream)
+	syslogStream := stream.(*logger.SyslogStream)
+	suite.Assert().Equal("tcp://localhost:6514", syslogStream.Address)
+	suite.Assert().Equal("rfc3164", syslogStream.Format)
+	suite.Assert().Equal("local0", syslogStream.Facility)
+	suite.Assert().Equal("myapp", syslogStream.AppName)
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), stream.GetFilterLevels())
+
+	clone := syslogStream.Clone().(*logger.SyslogStream)
+	suite.Assert().Equal(syslogStream.Address, clone.Address)
+	suite.Assert().Equal(syslogStream.Format, clone.Format)
+	suite.Assert().Equal(syslogStream.Facility, clone.Facility)
+	suite.Assert().Equal(syslogStream.AppName, clone.AppName)
+}
+
+func (suite *SyslogStreamSuite) TestCanStreamRFC5424OverUDP() {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer server.Close()
+
+	stream := &logger.SyslogStream{Address: "udp://" + server.LocalAddr().String(), Facility: "local0"}
+	defer stream.Close()
+	log := logger.Create("test", stream)
+	log.Record("quote", `say "hello" [there]`).Warnf("Hello World")
+
+	buffer := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := server.ReadFrom(buffer)
+	suite.Require().NoError(err)
+	message := string(buffer[:length])
+	suite.T().Logf("Message: %s", message)
+	pattern := regexp.MustCompile(`^<132>1 \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{6}Z \S+ test ` + strconv.Itoa(os.Getpid()) + ` - \[logger@32473 .*\] Hello World$`)
+	suite.Assert().Regexp(pattern, message)
+	suite.Assert().Contains(message, `quote="say \"hello\" [there\]"`)
+	suite.Assert().Contains(message, `scope="main"`)
+	suite.Assert().Contains(message, `topic="main"`)
+	suite.Assert().NotContains(message, `msg=`)
+}
+
+func (suite *SyslogStreamSuite) TestCanStreamRFC3164OverUnixSocket() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	socket := filepath.Join(folder, "log.sock")
+	server, err := net.ListenPacket("unixgram", socket)
+	suite.Require().NoError(err)
+	defer server.Close()
+
+	stream := &logger.SyslogStream{Address: socket, Format: "rfc3164", AppName: "myapp"}
+	defer stream.Close()
+	err = stream.Write(logger.NewRecord().Set("level", logger.ERROR).Set("time", time.Now()).Set("hostname", "myhost").Set("pid", 42).Set("msg", "Something went wrong"))
+	suite.Require().NoError(err)
+
+	buffer := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := server.ReadFrom(buffer)
+	suite.Require().NoError(err)
+	suite.Assert().Regexp(`^<11>[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} myhost myapp\[42\]: Something went wrong$`, string(buffer[:length]))
+}
+
+func (suite *SyslogStreamSuite) TestCanStreamOverTCPWithOctetCounting() {
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer server.Close()
+	messages := make(chan string, 2)
+	go func() {
+		connection, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		reader := bufio.NewReader(connection)
+		for {
+			header, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(strings.TrimSpace(header))
+			message := make([]byte, length)
+			if _, err = io.ReadFull(reader, message); err != nil {
+				return
+			}
+			messages <- string(message)
+		}
+	}()
+
+	stream := &logger.SyslogStream{Address: "tcp://" + server.Addr().String()}
+	defer stream.Close()
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("level", logger.INFO).Set("name", "test").Set("msg", "message 1")))
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("level", logger.DEBUG).Set("name", "test").Set("msg", "message 2")))
+
+	for i, expected := range []string{`^<14>1 - \S+ test - - - message 1$`, `^<15>1 - \S+ test - - - message 2$`} {
+		select {
+		case message := <-messages:
+			suite.Assert().Regexp(expected, message)
+		case <-time.After(time.Second):
+			suite.Failf("Timeout", "Did not receive message %d", i+1)
+		}
+	}
+}
+
+func (suite *SyslogStreamSuite) TestFailsWritingToMissingSocket() {
+	stream := &logger.SyslogStream{Address: "/path/to/nowhere.sock"}
+	err := stream.Write(logger.NewRecord().Set("msg", "Hello"))
+	suite.Require().Error(err, "Should have failed writing to stream")
+}
+
+func (suite *SyslogStreamSuite) TestCanSetFilterLevel() {
+	stream := &logger.SyslogStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+	stream.SetFilterLevel(logger.DEBUG, "main")
+	suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+	suite.Assert().False(stream.ShouldWrite(logger.DEBUG, "other", ""))
+	stream.FilterMore()
+	suite.Assert().Equal(logger.WARN, stream.FilterLevels.GetDefault())
+	stream.FilterLess()
+	suite.Assert().Equal(logger.INFO, stream.FilterLevels.GetDefault())
+}
+
+func (suite *SyslogStreamSuite) TestShouldUseEnvironmentPrefixForDefaultLevels() {
+	_ = os.Setenv("SYSLOGTEST_LOG_LEVEL", "WARN")
+	defer func() { _ = os.Unsetenv("SYSLOGTEST_LOG_LEVEL") }()
+
+	stream := logger.CreateStreamWithPrefix("SYSLOGTEST_", logger.LevelSet{}, "syslog:///path/to/nowhere.sock")
+	suite.Require().IsType(&logger.SyslogStream{}, stream)
+	_ = stream.Write(logger.NewRecord().Set("msg", "Hello"))
+	suite.Assert().Equal(logger.NewLevelSet(logger.WARN), stream.GetFilterLevels())
+	suite.Assert().Equal(logger.NewLevelSet(logger.WARN), stream.Clone().(*logger.SyslogStream).GetFilterLevels())
+}
@@ -1,7 +1,10 @@
 package logger_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -15,6 +18,7 @@ import (
 
 	"github.com/gildas/go-errors"
 	"github.com/gildas/go-logger"
+	"github.com/klauspost/compress/zstd"
 )
 
 type StreamSuite struct {
@@ -660,7 +664,256 @@ func (suite *StreamSuite) TestCanClone() {
 	suite.Assert().Equal(stackDriverStream.LogID, clonedStackDriverStream.(*logger.StackDriverStream).LogID)
 	suite.Assert().Equal(stackDriverStream.FilterLevels, clonedStackDriverStream.(*logger.StackDriverStream).FilterLevels)
 
+	writerStream := &logger.WriterStream{Writer: os.Stdout, FilterLevels: logger.NewLevelSet(logger.INFO)}
+	clonedWriterStream := writerStream.Clone()
+	suite.Assert().IsType(&logger.WriterStream{}, clonedWriterStream)
+	suite.Assert().Equal(writerStream.Writer, clonedWriterStream.(*logger.WriterStream).Writer)
+	suite.Assert().Equal(writerStream.FilterLevels, clonedWriterStream.(*logger.WriterStream).FilterLevels)
+
 	multiStream := logger.CreateMultiStream(stdoutStream, fileStream).(*logger.MultiStream)
 	clonedMultiStream := multiStream.Clone()
 	suite.Assert().IsType(&logger.MultiStream{}, clonedMultiStream)
 }
+
+func (suite *StreamSuite) TestCanRotateFileStreamBySize() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log"), MaxSize: 100}
+	defer stream.Close()
+
+	for i := 0; i < 10; i++ {
+		err := stream.Write(logger.NewRecord().Set("index", i).Set("bello", "banana"))
+		suite.Require().NoError(err)
+	}
+	stream.Flush()
+
+	backups, err := filepath.Glob(filepath.Join(folder, "test-*.log"))
+	suite.Require().NoError(err)
+	suite.Assert().NotEmpty(backups, "The file should have been rotated")
+
+	lines := 0
+	for _, filename := range append(backups, stream.Path) {
+		content, err := os.ReadFile(filename)
+		suite.Require().NoError(err, "Failed to read %s", filename)
+		suite.Assert().LessOrEqual(len(content), 100, "File %s should not be bigger than MaxSize", filename)
+		lines += strings.Count(string(content), "\n")
+	}
+	suite.Assert().Equal(10, lines, "No record should have been lost during rotations")
+}
+
+func (suite *StreamSuite) TestCanRotateFileStreamByInterval() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log"), RotationInterval: 50 * time.Millisecond, Unbuffered: true}
+	defer stream.Close()
+
+	err := stream.Write(logger.NewRecord().Set("bello", "banana"))
+	suite.Require().NoError(err)
+	time.Sleep(60 * time.Millisecond)
+	err = stream.Write(logger.NewRecord().Set("bello", "mata banana"))
+	suite.Require().NoError(err)
+
+	backups, err := filepath.Glob(filepath.Join(folder, "test-*.log"))
+	suite.Require().NoError(err)
+	suite.Require().Len(backups, 1, "The file should have been rotated once")
+	content, err := os.ReadFile(backups[0])
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"banana"}`, strings.TrimSpace(string(content)))
+	content, err = os.ReadFile(stream.Path)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"mata banana"}`, strings.TrimSpace(string(content)))
+}
+
+func (suite *StreamSuite) TestShouldKeepWritingWhenFileStreamFailsToRotate() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	// The name of the backups is too long for the file system, so the file cannot be renamed
+	stream := &logger.FileStream{Path: filepath.Join(folder, strings.Repeat("a", 240)+".log"), MaxSize: 50, Unbuffered: true}
+	defer stream.Close()
+
+	output := CaptureStderr(func() {
+		for i := 0; i < 5; i++ {
+			err := stream.Write(logger.NewRecord().Set("index", i).Set("bello", "banana"))
+			suite.Require().NoError(err, "The record should be written even if the rotation failed")
+		}
+	})
+	suite.Assert().Equal(1, strings.Count(output, "Logger error:"), "The rotation should not be tried again at every write")
+
+	content, err := os.ReadFile(stream.Path)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(5, strings.Count(string(content), "\n"), "No record should have been lost")
+}
+
+func (suite *StreamSuite) TestCanPurgeFileStreamBackups() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	old := filepath.Join(folder, "test-2001-01-01T00-00-00.000.log")
+	suite.Require().NoError(os.WriteFile(old, []byte("{}\n"), 0644))
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log"), MaxSize: 10, MaxBackups: 2, MaxAge: 24 * time.Hour, Unbuffered: true}
+	defer stream.Close()
+
+	for i := 0; i < 5; i++ {
+		err := stream.Write(logger.NewRecord().Set("index", i))
+		suite.Require().NoError(err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(folder, "test-*.log"))
+	suite.Require().NoError(err)
+	suite.Assert().Len(backups, 2, "Only MaxBackups files should be kept")
+	suite.Assert().NotContains(backups, old, "Files older than MaxAge should be deleted")
+}
+
+func (suite *StreamSuite) TestCanCreateRotatingFileStreamFromDestination() {
+	stream := logger.CreateStream(logger.NewLevelSet(logger.INFO), "file://./log/test.log?maxsize=10MB&rotate=daily&maxbackups=5&maxage=7d")
+	suite.Require().NotNil(stream, "Failed to create a file stream")
+	suite.Require().IsType(&logger.FileStream{}, stream)
+	fileStream := stream.(*logger.FileStream)
+	suite.Assert().Equal("./log/test.log", fileStream.Path)
+	suite.Assert().Equal(int64(10*1000*1000), fileStream.MaxSize)
+	suite.Assert().Equal(24*time.Hour, fileStream.RotationInterval)
+	suite.Assert().Equal(5, fileStream.MaxBackups)
+	suite.Assert().Equal(7*24*time.Hour, fileStream.MaxAge)
+	suite.Assert().Equal(logger.NewLevelSet(logger.INFO), stream.GetFilterLevels())
+
+	clone := fileStream.Clone().(*logger.FileStream)
+	suite.Assert().Equal(fileStream.MaxSize, clone.MaxSize)
+	suite.Assert().Equal(fileStream.RotationInterval, clone.RotationInterval)
+	suite.Assert().Equal(fileStream.MaxBackups, clone.MaxBackups)
+	suite.Assert().Equal(fileStream.MaxAge, clone.MaxAge)
+}
+
+func (suite *StreamSuite) TestCanCompressRotatedFilesWithGzip() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log"), MaxSize: 10, Compression: "gzip", Unbuffered: true}
+
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("bello", "banana")))
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("bello", "mata banana")))
+	stream.Close() // waits for the compression to finish
+
+	uncompressed, _ := filepath.Glob(filepath.Join(folder, "test-*.log"))
+	suite.Assert().Empty(uncompressed, "Rotated files should have been compressed")
+	backups, err := filepath.Glob(filepath.Join(folder, "test-*.log.gz"))
+	suite.Require().NoError(err)
+	suite.Require().Len(backups, 1)
+
+	file, err := os.Open(backups[0])
+	suite.Require().NoError(err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	suite.Require().NoError(err)
+	content, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"banana"}`, strings.TrimSpace(string(content)))
+
+	content, err = os.ReadFile(stream.Path)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"mata banana"}`, strings.TrimSpace(string(content)), "The live file should not be compressed")
+}
+
+func (suite *StreamSuite) TestCanCompressRotatedFilesWithZstd() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := logger.CreateStream(logger.NewLevelSet(logger.INFO), "file://"+filepath.Join(folder, "test.log")+"?maxsize=10&compress=zstd&maxbackups=1").(*logger.FileStream)
+	suite.Require().Equal("zstd", stream.Compression)
+
+	for i := 0; i < 3; i++ {
+		suite.Require().NoError(stream.Write(logger.NewRecord().Set("index", i)))
+	}
+	stream.Close()
+
+	backups, err := filepath.Glob(filepath.Join(folder, "test-*.log.zst"))
+	suite.Require().NoError(err)
+	suite.Require().Len(backups, 1, "Only MaxBackups compressed files should be kept")
+
+	file, err := os.Open(backups[0])
+	suite.Require().NoError(err)
+	defer file.Close()
+	reader, err := zstd.NewReader(file)
+	suite.Require().NoError(err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"index":1}`, strings.TrimSpace(string(content)))
+}
+
+func (suite *StreamSuite) TestCanReopenFileStream() {
+	folder, teardown := CreateTempDir()
+	defer teardown()
+	stream := &logger.FileStream{Path: filepath.Join(folder, "test.log")}
+	defer stream.Close()
+	suite.Require().NoError(stream.Reopen(), "Reopening a stream that never wrote should do nothing")
+
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("bello", "banana")))
+	moved := filepath.Join(folder, "test.log.1")
+	suite.Require().NoError(os.Rename(stream.Path, moved))
+	multi := logger.CreateMultiStream(stream, &logger.NilStream{})
+	suite.Require().NoError(multi.(logger.Reopener).Reopen())
+	suite.Require().NoError(stream.Write(logger.NewRecord().Set("bello", "mata banana")))
+	stream.Flush()
+
+	content, err := os.ReadFile(moved)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"banana"}`, strings.TrimSpace(string(content)), "Buffered records should have been flushed to the moved file")
+	content, err = os.ReadFile(stream.Path)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"mata banana"}`, strings.TrimSpace(string(content)))
+}
+
+func (suite *StreamSuite) TestCanStreamToWriter() {
+	output := &bytes.Buffer{}
+	stream := &logger.WriterStream{Writer: output, FilterLevels: logger.NewLevelSet(logger.INFO)}
+	defer stream.Close()
+	suite.Assert().Equal("Stream to writer *bytes.Buffer, Filter: INFO", stream.String())
+
+	err := stream.Write(logger.NewRecord().Set("bello", "banana").Set("だれ", "私"))
+	suite.Require().NoError(err)
+	suite.Assert().Empty(output.String(), "The stream should be buffered")
+	err = stream.Write(logger.NewRecord().Set("level", logger.ERROR).Set("key1", "value1"))
+	suite.Require().NoError(err)
+
+	lines := strings.Split(output.String(), "\n")
+	suite.Require().Len(lines, 3, "Should have written 2 lines when an error was written")
+	suite.Assert().JSONEq(`{"bello":"banana","だれ":"私"}`, lines[0])
+	suite.Assert().JSONEq(`{"level":50,"key1":"value1"}`, lines[1])
+}
+
+func (suite *StreamSuite) TestCanStreamToUnbufferedWriterWithEncoder() {
+	output := &bytes.Buffer{}
+	encoder := logger.EncoderFunc(func(record *logger.Record) ([]byte, error) {
+		return []byte(fmt.Sprintf("%s: %s\n", record.Get("level"), record.Get("msg"))), nil
+	})
+	stream := &logger.WriterStream{Writer: output, Encoder: encoder, Unbuffered: true}
+	log := logger.Create("test", stream)
+	log.Infof("Hello")
+	log.Warnf("World")
+	suite.Assert().Equal("INFO: Hello\nWARN: World\n", output.String())
+}
+
+func (suite *StreamSuite) TestCanStreamToWriterAndFlushIt() {
+	_ = os.Setenv("LOG_FLUSHFREQUENCY", "10ms")
+	defer func() { _ = os.Unsetenv("LOG_FLUSHFREQUENCY") }()
+	output := &bytes.Buffer{}
+	compressor := gzip.NewWriter(output)
+	stream := &logger.WriterStream{Writer: compressor}
+
+	err := stream.Write(logger.NewRecord().Set("bello", "banana"))
+	suite.Require().NoError(err)
+	time.Sleep(50 * time.Millisecond)
+	stream.Close()
+	suite.Require().NotZero(output.Len(), "The gzip writer should have been flushed")
+	suite.Require().NoError(compressor.Close())
+
+	reader, err := gzip.NewReader(output)
+	suite.Require().NoError(err)
+	content, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"bello":"banana"}`, strings.TrimSpace(string(content)))
+}
+
+func (suite *StreamSuite) TestFailsWritingToWriterStreamWithoutWriter() {
+	stream := &logger.WriterStream{}
+	err := stream.Write(logger.NewRecord().Set("bello", "banana"))
+	suite.Require().Error(err, "Should have failed writing to stream")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
@@ -0,0 +1,112 @@
+package logger_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gildas/go-logger"
+)
+
+// WrapperStreamSuite tests the behavior shared by the Streams that write to a Destination
+type WrapperStreamSuite struct {
+	suite.Suite
+	Name string
+}
+
+// wrapperStreams creates the Streams that write to a Destination, with the String they should have
+//
+// buffers tells if the Stream accepts the records its Destination filters out
+var wrapperStreams = []struct {
+	name    string
+	create  func(destination logger.Streamer) logger.Streamer
+	text    string
+	buffers bool
+}{
+	{
+		name: "Async",
+		create: func(destination logger.Streamer) logger.Streamer {
+			return &logger.AsyncStream{Destination: destination, OverflowPolicy: logger.OverflowDropNewest}
+		},
+		text: "Async Stream (drop-newest) to Stream to stdout, Filter: INFO",
+	},
+	{
+		name: "Sampling",
+		create: func(destination logger.Streamer) logger.Streamer {
+			return &logger.SamplingStream{Destination: destination, First: 10, Thereafter: 20, Tick: time.Minute}
+		},
+		text: "Sampling Stream (first: 10, thereafter: 20, tick: 1m0s) to Stream to stdout, Filter: INFO",
+	},
+	{
+		name: "Dedup",
+		create: func(destination logger.Streamer) logger.Streamer {
+			return &logger.DedupStream{Destination: destination, Window: time.Minute}
+		},
+		text: "Dedup Stream (window: 1m0s) to Stream to stdout, Filter: INFO",
+	},
+	{
+		name: "FingersCrossed",
+		create: func(destination logger.Streamer) logger.Streamer {
+			return &logger.FingersCrossedStream{Destination: destination, Key: "session", ActivationLevel: logger.WARN}
+		},
+		text:    "Fingers Crossed Stream (key: session, activation: WARN) to Stream to stdout, Filter: INFO",
+		buffers: true,
+	},
+}
+
+func TestWrapperStreamSuite(t *testing.T) {
+	suite.Run(t, new(WrapperStreamSuite))
+}
+
+func (suite *WrapperStreamSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(suite).Elem().Name(), "Suite")
+}
+
+func (suite *WrapperStreamSuite) TestFailsWritingWithoutDestination() {
+	for _, test := range wrapperStreams {
+		suite.Run(test.name, func() {
+			stream := test.create(nil)
+			suite.Assert().Error(stream.Write(logger.NewRecord().Set("msg", "Hello")), "Stream should have failed writing without a Destination")
+		})
+	}
+}
+
+func (suite *WrapperStreamSuite) TestCanSetFilterLevelOfDestination() {
+	for _, test := range wrapperStreams {
+		suite.Run(test.name, func() {
+			destination := &logger.StdoutStream{FilterLevels: logger.NewLevelSet(logger.INFO)}
+			stream := test.create(destination)
+			suite.Assert().Equal(test.text, stream.(interface{ String() string }).String())
+
+			stream.(logger.FilterSetter).SetFilterLevel(logger.DEBUG, "main")
+			suite.Assert().Equal(logger.DEBUG, destination.FilterLevels.Get("main", ""))
+			suite.Assert().Equal(destination.FilterLevels, stream.GetFilterLevels())
+			suite.Assert().True(stream.ShouldWrite(logger.DEBUG, "main", ""))
+			suite.Assert().Equal(test.buffers, stream.ShouldWrite(logger.DEBUG, "other", ""))
+
+			stream.(logger.FilterModifier).FilterMore()
+			suite.Assert().Equal(logger.WARN, destination.FilterLevels.GetDefault())
+			stream.(logger.FilterModifier).FilterLess()
+			suite.Assert().Equal(logger.INFO, destination.FilterLevels.GetDefault())
+		})
+	}
+}
+
+func (suite *WrapperStreamSuite) TestCanClone() {
+	for _, test := range wrapperStreams {
+		suite.Run(test.name, func() {
+			stream := test.create(&logger.StdoutStream{FilterLevels: logger.NewLevelSet(logger.INFO)})
+			clone := stream.Clone()
+			suite.Require().IsType(stream, clone)
+			suite.Assert().NotSame(stream, clone)
+			suite.Assert().Equal(test.text, clone.(interface{ String() string }).String(), "Stream should keep its settings")
+
+			destination := reflect.ValueOf(stream).Elem().FieldByName("Destination").Interface()
+			clonedDestination := reflect.ValueOf(clone).Elem().FieldByName("Destination").Interface()
+			suite.Assert().NotSame(destination, clonedDestination, "Stream should clone its Destination")
+		})
+	}
+}